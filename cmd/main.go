@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -15,8 +16,14 @@ import (
 	"github.com/Wladim1r/statcounter/internal/api/service"
 	"github.com/Wladim1r/statcounter/internal/auth"
 	"github.com/Wladim1r/statcounter/internal/db"
+	"github.com/Wladim1r/statcounter/internal/lib/backfill"
 	"github.com/Wladim1r/statcounter/internal/lib/logger"
+	"github.com/Wladim1r/statcounter/internal/lib/notify"
+	"github.com/Wladim1r/statcounter/internal/lib/pdf"
+	"github.com/Wladim1r/statcounter/internal/lib/retention"
 	"github.com/Wladim1r/statcounter/internal/lib/routes"
+	"github.com/Wladim1r/statcounter/internal/lib/schedule"
+	"github.com/Wladim1r/statcounter/internal/lib/summa"
 	"github.com/Wladim1r/statcounter/internal/lib/tick"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
@@ -36,17 +43,145 @@ func main() {
 		log.Printf("Error initialize regions and users: %v", err)
 	}
 
-	// initialize services
-	repo := repository.NewDjnRepo(db)
-	serv := service.NewDjnService(repo)
-	hand := handler.NewDjnHandler(serv)
-
 	// initialize authorization
 	authService := auth.NewAuthService(db)
 	authController := auth.NewAuthController(authService)
 
+	// Загружаем и проверяем политику хранения отчетов
+	regions, err := authService.GetRegions()
+	if err != nil {
+		panic(err)
+	}
+	policy, err := retention.FromEnv(regions)
+	if err != nil {
+		panic(err)
+	}
+	backfillPolicy, err := backfill.FromEnv()
+	if err != nil {
+		panic(err)
+	}
+	reconcileInterval, err := tick.ReconcileIntervalFromEnv()
+	if err != nil {
+		panic(err)
+	}
+
+	digestTimes, err := schedule.FromEnv("DIGEST_TIME", "REGION_DIGEST_TIMES", regions)
+	if err != nil {
+		panic(err)
+	}
+	deadlines, err := schedule.FromEnv("REPORT_DEADLINE", "REGION_REPORT_DEADLINES", regions)
+	if err != nil {
+		panic(err)
+	}
+	remindBefore, err := tick.RemindBeforeFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	notifiers, err := notify.FromEnv()
+	if err != nil {
+		panic(err)
+	}
+
+	// Без шрифта с кириллицей PDF-сводки недоступны, остальное работает
+	font, err := pdf.FontFromEnv()
+	if err != nil {
+		log.Printf("PDF reports are disabled: %v", err)
+	}
+
+	// Выбираем агрегатор и восстанавливаем агрегированную статистику из БД
+	aggregator, err := summa.NewAggregator(os.Getenv("AGGREGATOR"), db)
+	if err != nil {
+		panic(err)
+	}
+	if err := aggregator.Rebuild(); err != nil {
+		log.Printf("Error initialize regional stats: %v", err)
+	}
+
+	// initialize services
+	repo := repository.NewDjnRepo(db, aggregator)
+	productRepo := repository.NewProductRepo(db)
+	metricRepo := repository.NewMetricRepo(db)
+	archiveRepo := repository.NewArchiveRepo(db)
+	revisionRepo := repository.NewRevisionRepo(db)
+	planRepo := repository.NewPlanRepo(db)
+	calendarRepo := repository.NewCalendarRepo(db)
+	scoringRepo := repository.NewScoringRepo(db)
+	bonusRepo := repository.NewBonusRepo(db)
+	notificationRepo := repository.NewNotificationRepo(db)
+
+	calendarServ := service.NewCalendarService(calendarRepo, authService)
+	scoringServ := service.NewScoringService(scoringRepo, productRepo, metricRepo, authService)
+	planServ := service.NewPlanService(planRepo, productRepo, authService, calendarServ)
+	serv := service.NewDjnService(
+		repo,
+		productRepo,
+		metricRepo,
+		policy,
+		backfillPolicy,
+		authService,
+		planServ,
+		scoringServ,
+	)
+	productServ := service.NewProductService(productRepo)
+	metricServ := service.NewMetricService(metricRepo)
+	archiveServ := service.NewArchiveService(archiveRepo, repo)
+	adminStatsServ := service.NewAdminStatsService(repo, productRepo, authService, scoringServ)
+	revisionServ := service.NewRevisionService(revisionRepo, repo, productRepo, metricRepo, policy)
+	pacingServ := service.NewPacingService(planRepo, productRepo, calendarServ, archiveServ)
+	leaderboardServ := service.NewLeaderboardService(repo, authService, scoringServ, policy, archiveServ)
+	bonusServ := service.NewBonusService(bonusRepo, productRepo, metricRepo, authService, archiveServ)
+	dailyReportServ := service.NewDailyReportService(adminStatsServ, productRepo, metricRepo, policy, font)
+	missingServ := service.NewMissingService(
+		repo,
+		authService,
+		calendarServ,
+		policy,
+		deadlines,
+		remindBefore,
+		notificationRepo,
+		notifiers,
+	)
+	digestServ := service.NewDigestService(
+		adminStatsServ,
+		dailyReportServ,
+		missingServ,
+		productRepo,
+		metricRepo,
+		authService,
+		notificationRepo,
+		notifiers,
+	)
+
+	hand := handler.NewDjnHandler(serv, productServ, metricServ)
+	productHand := handler.NewProductHandler(productServ)
+	metricHand := handler.NewMetricHandler(metricServ)
+	archiveHand := handler.NewArchiveHandler(archiveServ)
+	adminStatsHand := handler.NewAdminStatsHandler(adminStatsServ)
+	revisionHand := handler.NewRevisionHandler(revisionServ)
+	planHand := handler.NewPlanHandler(planServ)
+	calendarHand := handler.NewCalendarHandler(calendarServ)
+	pacingHand := handler.NewPacingHandler(pacingServ)
+	leaderboardHand := handler.NewLeaderboardHandler(leaderboardServ)
+	scoringHand := handler.NewScoringHandler(scoringServ)
+	bonusHand := handler.NewBonusHandler(bonusServ)
+	dailyReportHand := handler.NewDailyReportHandler(dailyReportServ)
+	digestHand := handler.NewDigestHandler(digestServ)
+	missingHand := handler.NewMissingHandler(missingServ)
+
 	router := gin.Default()
 
+	// Адрес клиента берется из X-Forwarded-For только от доверенных прокси,
+	// иначе его можно подделать в журнале аудита. Пустой TRUSTED_PROXIES - не доверять никому
+	var trustedProxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		panic(fmt.Errorf("invalid TRUSTED_PROXIES: %w", err))
+	}
+
 	router.LoadHTMLGlob("web/templates/*.html")
 
 	// session settings
@@ -85,7 +220,25 @@ func main() {
 
 	router.Use(gin.LoggerWithFormatter(logger.Log))
 
-	routes.SetupRoutes(router, authController, &hand)
+	routes.SetupRoutes(
+		router,
+		authController,
+		&hand,
+		&productHand,
+		&metricHand,
+		&archiveHand,
+		&adminStatsHand,
+		&revisionHand,
+		&planHand,
+		&calendarHand,
+		&pacingHand,
+		&leaderboardHand,
+		&scoringHand,
+		&bonusHand,
+		&dailyReportHand,
+		&digestHand,
+		&missingHand,
+	)
 
 	ctx, stop := signal.NotifyContext(context.Background(),
 		syscall.SIGHUP,
@@ -104,7 +257,18 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
-	go tick.TruncateToTickerMonthlyWithContext(ctx, repo)
+	go tick.TruncateToTickerMonthlyWithContext(ctx, repo, policy, authService)
+	go tick.SyncStatsWithContext(ctx, repo, reconcileInterval)
+	if len(notifiers) > 0 && digestTimes.Enabled() {
+		go tick.SendDigestsWithContext(ctx, digestServ, calendarServ, digestTimes, authService)
+	} else {
+		log.Printf("daily digests are disabled: no notification channels or DIGEST_TIME configured")
+	}
+	if len(notifiers) > 0 && deadlines.Enabled() {
+		go tick.SendRemindersWithContext(ctx, missingServ, deadlines, authService)
+	} else {
+		log.Printf("report reminders are disabled: no notification channels or REPORT_DEADLINE configured")
+	}
 
 	log.Printf("server start on potr %s\n", os.Getenv("SVR_PORT"))
 	log.Printf("database info configuration\n")
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Wladim1r/statcounter/internal/api/service"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminStatsHandler struct {
+	serv service.AdminStatsService
+}
+
+func NewAdminStatsHandler(serv service.AdminStatsService) AdminStatsHandler {
+	return AdminStatsHandler{serv: serv}
+}
+
+// Итоги по всем регионам и по компании; фильтры date и product
+func (h *AdminStatsHandler) GetAllRegionalStats(c *gin.Context) {
+	stats, err := h.serv.GetCompanyStats(c.Query("date"), c.Query("product"))
+	if err != nil {
+		writeAdminStatsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// Отчеты пользователей выбранного региона; фильтры date и product
+func (h *AdminStatsHandler) GetRegionReports(c *gin.Context) {
+	regionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid region ID",
+		})
+		return
+	}
+
+	reports, err := h.serv.GetRegionReports(uint(regionID), c.Query("date"), c.Query("product"))
+	if err != nil {
+		writeAdminStatsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
+
+func writeAdminStatsError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errs.ErrBadRequest):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Region not found",
+		})
+	case errors.Is(err, errs.ErrDBOperation):
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Database operation failed",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Internal server error",
+		})
+	}
+}
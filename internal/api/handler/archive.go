@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Wladim1r/statcounter/internal/api/service"
+	"github.com/Wladim1r/statcounter/internal/auth"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type ArchiveHandler struct {
+	serv service.ArchiveService
+}
+
+func NewArchiveHandler(serv service.ArchiveService) ArchiveHandler {
+	return ArchiveHandler{serv: serv}
+}
+
+func (h *ArchiveHandler) GetMonthlyArchive(c *gin.Context) {
+	regionID := auth.GetRegionIDFromContext(c)
+	if regionID == 0 {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error: "Region is not defined",
+		})
+		return
+	}
+
+	var username string
+	if c.Query("user") == "true" {
+		username = c.GetString("username")
+	}
+
+	archive, err := h.serv.GetMonthlyArchive(regionID, c.Query("month"), username)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrBadRequest):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: err.Error(),
+			})
+		case errors.Is(err, errs.ErrNotFound):
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error: "Record not found",
+			})
+		case errors.Is(err, errs.ErrDBOperation):
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Database operation failed",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Internal server error",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, archive)
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Wladim1r/statcounter/internal/api/service"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type BonusHandler struct {
+	serv service.BonusService
+}
+
+func NewBonusHandler(serv service.BonusService) BonusHandler {
+	return BonusHandler{serv: serv}
+}
+
+func (h *BonusHandler) GetRules(c *gin.Context) {
+	rules, err := h.serv.GetRules()
+	if err != nil {
+		writeBonusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+func (h *BonusHandler) CreateRule(c *gin.Context) {
+	var req service.BonusRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid Body Request",
+		})
+		return
+	}
+
+	rule, err := h.serv.CreateRule(req, c.GetString("username"))
+	if err != nil {
+		writeBonusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (h *BonusHandler) UpdateRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid rule ID",
+		})
+		return
+	}
+
+	var req service.BonusRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid Body Request",
+		})
+		return
+	}
+
+	rule, err := h.serv.UpdateRule(uint(id), req, c.GetString("username"))
+	if err != nil {
+		writeBonusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *BonusHandler) DeleteRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid rule ID",
+		})
+		return
+	}
+
+	if err := h.serv.DeleteRule(uint(id)); err != nil {
+		writeBonusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bonus rule deleted successfully",
+		"status":  "success",
+	})
+}
+
+// Расчеты премий (?month=YYYY-MM)
+func (h *BonusHandler) GetRuns(c *gin.Context) {
+	runs, err := h.serv.GetRuns(c.Query("month"))
+	if err != nil {
+		writeBonusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+func (h *BonusHandler) GetRun(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid run ID",
+		})
+		return
+	}
+
+	run, err := h.serv.GetRun(uint(id))
+	if err != nil {
+		writeBonusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// Рассчитать или пересчитать черновик премий региона за месяц
+func (h *BonusHandler) Calculate(c *gin.Context) {
+	var req service.BonusRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid Body Request",
+		})
+		return
+	}
+
+	run, err := h.serv.Calculate(req, c.GetString("username"))
+	if err != nil {
+		writeBonusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// Утвердить расчет, после этого он не пересчитывается и не удаляется
+func (h *BonusHandler) LockRun(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid run ID",
+		})
+		return
+	}
+
+	run, err := h.serv.LockRun(uint(id), c.GetString("username"))
+	if err != nil {
+		writeBonusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+func (h *BonusHandler) DeleteRun(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid run ID",
+		})
+		return
+	}
+
+	if err := h.serv.DeleteRun(uint(id)); err != nil {
+		writeBonusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bonus run deleted successfully",
+		"status":  "success",
+	})
+}
+
+// Выгрузка начислений расчета в CSV
+func (h *BonusHandler) ExportRun(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid run ID",
+		})
+		return
+	}
+
+	filename, data, err := h.serv.ExportRun(uint(id))
+	if err != nil {
+		writeBonusError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+}
+
+func writeBonusError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errs.ErrBadRequest):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Record not found",
+		})
+	case errors.Is(err, errs.ErrConflict):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrDBOperation):
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Database operation failed",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Internal server error",
+		})
+	}
+}
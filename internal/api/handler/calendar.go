@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/service"
+	"github.com/Wladim1r/statcounter/internal/auth"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Максимальный размер импортируемого файла календаря
+const maxCalendarFileSize = 1 << 20
+
+type CalendarHandler struct {
+	serv service.CalendarService
+}
+
+func NewCalendarHandler(serv service.CalendarService) CalendarHandler {
+	return CalendarHandler{serv: serv}
+}
+
+// Календарь региона пользователя за месяц (?month=YYYY-MM, по умолчанию текущий)
+func (h *CalendarHandler) GetMonthCalendar(c *gin.Context) {
+	regionID := auth.GetRegionIDFromContext(c)
+	if regionID == 0 {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error: "Region is not defined",
+		})
+		return
+	}
+
+	month := c.DefaultQuery("month", time.Now().Format("2006-01"))
+	from, err := time.ParseInLocation("2006-01", month, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid month format. Use YYYY-MM",
+		})
+		return
+	}
+
+	calendar, err := h.serv.GetCalendar(regionID, from, from.AddDate(0, 1, -1))
+	if err != nil {
+		writeCalendarError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, calendar)
+}
+
+// Календарь региона за год (?region_id&year, region_id 0 - общий календарь)
+func (h *CalendarHandler) GetCalendar(c *gin.Context) {
+	regionID, err := strconv.ParseUint(c.DefaultQuery("region_id", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid region ID",
+		})
+		return
+	}
+
+	year, err := strconv.Atoi(c.DefaultQuery("year", strconv.Itoa(time.Now().Year())))
+	if err != nil || year < 1 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid year",
+		})
+		return
+	}
+
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.Local)
+	calendar, err := h.serv.GetCalendar(uint(regionID), from, from.AddDate(1, 0, -1))
+	if err != nil {
+		writeCalendarError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, calendar)
+}
+
+func (h *CalendarHandler) SetWeekends(c *gin.Context) {
+	var req service.WorkWeekRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid Body Request",
+		})
+		return
+	}
+
+	week, err := h.serv.SetWeekends(req, c.GetString("username"))
+	if err != nil {
+		writeCalendarError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, week)
+}
+
+func (h *CalendarHandler) SaveDays(c *gin.Context) {
+	var req []service.CalendarDayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid Body Request",
+		})
+		return
+	}
+
+	if err := h.serv.SaveDays(req, c.GetString("username")); err != nil {
+		writeCalendarError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Calendar days saved successfully",
+		"status":  "success",
+	})
+}
+
+func (h *CalendarHandler) DeleteDay(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid day ID",
+		})
+		return
+	}
+
+	if err := h.serv.DeleteDay(uint(id)); err != nil {
+		writeCalendarError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Calendar day deleted successfully",
+		"status":  "success",
+	})
+}
+
+// Импорт праздников из файла CSV или iCal (multipart, поле file; ?region_id)
+func (h *CalendarHandler) ImportHolidays(c *gin.Context) {
+	regionID, err := strconv.ParseUint(c.DefaultQuery("region_id", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid region ID",
+		})
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "File is required",
+		})
+		return
+	}
+	if header.Size > maxCalendarFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{
+			Error: "File is too large",
+		})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Failed to read file",
+		})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxCalendarFileSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Failed to read file",
+		})
+		return
+	}
+
+	imported, err := h.serv.ImportHolidays(uint(regionID), header.Filename, data, c.GetString("username"))
+	if err != nil {
+		writeCalendarError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Holidays imported successfully",
+		"status":   "success",
+		"imported": imported,
+	})
+}
+
+func writeCalendarError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errs.ErrBadRequest):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Calendar day not found",
+		})
+	case errors.Is(err, errs.ErrDBOperation):
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Database operation failed",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Internal server error",
+		})
+	}
+}
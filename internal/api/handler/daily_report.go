@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Wladim1r/statcounter/internal/api/service"
+	"github.com/Wladim1r/statcounter/internal/auth"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type DailyReportHandler struct {
+	serv service.DailyReportService
+}
+
+func NewDailyReportHandler(serv service.DailyReportService) DailyReportHandler {
+	return DailyReportHandler{serv: serv}
+}
+
+// PDF-сводка региона пользователя за дату (?date=YYYY-MM-DD, по умолчанию сегодня)
+func (h *DailyReportHandler) GetDailyReport(c *gin.Context) {
+	regionID := auth.GetRegionIDFromContext(c)
+	if regionID == 0 {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error: "Region is not defined",
+		})
+		return
+	}
+
+	h.writeReport(c, regionID)
+}
+
+// PDF-сводка любого региона (?region_id&date)
+func (h *DailyReportHandler) GetRegionDailyReport(c *gin.Context) {
+	regionID, err := strconv.ParseUint(c.Query("region_id"), 10, 64)
+	if err != nil || regionID == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid region ID",
+		})
+		return
+	}
+
+	h.writeReport(c, uint(regionID))
+}
+
+func (h *DailyReportHandler) writeReport(c *gin.Context, regionID uint) {
+	data, filename, err := h.serv.RenderDailyReport(regionID, c.Query("date"))
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrBadRequest):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: err.Error(),
+			})
+		case errors.Is(err, errs.ErrNotFound):
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error: err.Error(),
+			})
+		case errors.Is(err, errs.ErrUnavailable):
+			c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+				Error: err.Error(),
+			})
+		case errors.Is(err, errs.ErrDBOperation):
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Database operation failed",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Internal server error",
+			})
+		}
+		return
+	}
+
+	// inline - сводка открывается в браузере для печати, ?download=true - скачивание файлом
+	disposition := "inline"
+	if c.Query("download") == "true" {
+		disposition = "attachment"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, filename))
+	c.Data(http.StatusOK, "application/pdf", data)
+}
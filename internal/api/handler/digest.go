@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Wladim1r/statcounter/internal/api/service"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type DigestHandler struct {
+	serv service.DigestService
+}
+
+func NewDigestHandler(serv service.DigestService) DigestHandler {
+	return DigestHandler{serv: serv}
+}
+
+// Предпросмотр сводки региона (?region_id&date)
+func (h *DigestHandler) GetDigest(c *gin.Context) {
+	regionID, ok := digestRegionID(c)
+	if !ok {
+		return
+	}
+
+	digest, err := h.serv.ComposeDigest(regionID, c.Query("date"))
+	if err != nil {
+		writeDigestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, digest)
+}
+
+// Отправить сводку региона сейчас (?region_id&date, ?force=true - повторить уже доставленные)
+func (h *DigestHandler) SendDigest(c *gin.Context) {
+	regionID, ok := digestRegionID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.serv.SendDigest(
+		c.Request.Context(),
+		regionID,
+		c.Query("date"),
+		c.Query("force") == "true",
+	)
+	if err != nil {
+		writeDigestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Журнал уведомлений региона за дату (?region_id&date&kind)
+func (h *DigestHandler) GetNotifications(c *gin.Context) {
+	regionID, ok := digestRegionID(c)
+	if !ok {
+		return
+	}
+
+	entries, err := h.serv.GetNotifications(regionID, c.Query("date"), c.Query("kind"))
+	if err != nil {
+		writeDigestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+func digestRegionID(c *gin.Context) (uint, bool) {
+	regionID, err := strconv.ParseUint(c.Query("region_id"), 10, 64)
+	if err != nil || regionID == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid region ID",
+		})
+		return 0, false
+	}
+
+	return uint(regionID), true
+}
+
+func writeDigestError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errs.ErrBadRequest):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrConflict):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrUnavailable):
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrDBOperation):
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Database operation failed",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Internal server error",
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Wladim1r/statcounter/internal/api/service"
+	"github.com/Wladim1r/statcounter/internal/auth"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/lib/export"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Выгрузка отчетов своего региона (?format=csv|xlsx, ?date или ?from&to, ?user=true - только свои)
+func (h *DjnHandler) ExportStats(c *gin.Context) {
+	regionID := auth.GetRegionIDFromContext(c)
+	if regionID == 0 {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error: "Region is not defined",
+		})
+		return
+	}
+
+	var username string
+	if c.Query("user") == "true" {
+		username = c.GetString("username")
+	}
+
+	h.writeExport(c, regionID, username)
+}
+
+// Выгрузка отчетов любого региона (?region_id&name, остальные параметры как у ExportStats)
+func (h *DjnHandler) ExportRegionStats(c *gin.Context) {
+	regionID, err := strconv.ParseUint(c.Query("region_id"), 10, 64)
+	if err != nil || regionID == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid region ID",
+		})
+		return
+	}
+
+	h.writeExport(c, uint(regionID), c.Query("name"))
+}
+
+func (h *DjnHandler) writeExport(c *gin.Context, regionID uint, username string) {
+	format, err := export.ParseFormat(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	table, filename, err := h.serv.ExportStats(service.ExportQuery{
+		RegionID: regionID,
+		Name:     username,
+		Date:     c.Query("date"),
+		From:     c.Query("from"),
+		To:       c.Query("to"),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrBadRequest):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: err.Error(),
+			})
+		case errors.Is(err, errs.ErrDBOperation):
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Database operation failed",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Internal server error",
+			})
+		}
+		return
+	}
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+"."+format))
+	c.Status(http.StatusOK)
+
+	// Заголовки уже отправлены, поэтому ошибку записи можно только залогировать
+	if err := export.Write(c.Writer, format, "Отчеты", *table); err != nil {
+		log.Printf("Error writing export %s: %v", filename, err)
+	}
+}
@@ -2,7 +2,6 @@ package handler
 
 import (
 	"errors"
-	"fmt"
 	"log"
 	"net/http"
 	"time"
@@ -10,20 +9,31 @@ import (
 	"github.com/Wladim1r/statcounter/internal/api/service"
 	"github.com/Wladim1r/statcounter/internal/auth"
 	"github.com/Wladim1r/statcounter/internal/lib/errs"
-	"github.com/Wladim1r/statcounter/internal/lib/summa"
 	"github.com/Wladim1r/statcounter/internal/models"
 	"github.com/gin-gonic/gin"
 )
 
 type DjnHandler struct {
-	serv service.DjnService
+	serv     service.DjnService
+	products service.ProductService
+	metrics  service.MetricService
 }
 
-func NewDjnHandler(serv service.DjnService) DjnHandler {
-	return DjnHandler{serv: serv}
+func NewDjnHandler(
+	serv service.DjnService,
+	products service.ProductService,
+	metrics service.MetricService,
+) DjnHandler {
+	return DjnHandler{serv: serv, products: products, metrics: metrics}
 }
 
 func (h *DjnHandler) GetStatByMonth(c *gin.Context) {
+	// С параметрами from/to отдаем статистику за период
+	if c.Query("from") != "" || c.Query("to") != "" {
+		h.GetStatsByRange(c)
+		return
+	}
+
 	regionID := auth.GetRegionIDFromContext(c)
 	username := c.GetString("username")
 
@@ -43,6 +53,10 @@ func (h *DjnHandler) GetStatByMonth(c *gin.Context) {
 
 	if err != nil {
 		switch {
+		case errors.Is(err, errs.ErrBadRequest):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: err.Error(),
+			})
 		case errors.Is(err, errs.ErrNotFound):
 			c.JSON(http.StatusNotFound, models.ErrorResponse{
 				Error: "Record not found",
@@ -62,6 +76,48 @@ func (h *DjnHandler) GetStatByMonth(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+func (h *DjnHandler) GetStatsByRange(c *gin.Context) {
+	regionID := auth.GetRegionIDFromContext(c)
+	if regionID == 0 {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error: "Region is not defined",
+		})
+		return
+	}
+
+	var username string
+	if c.Query("user") == "true" {
+		username = c.GetString("username")
+	}
+
+	stats, err := h.serv.GetStatsByRange(
+		regionID,
+		username,
+		c.Query("from"),
+		c.Query("to"),
+		c.Query("group"),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrBadRequest):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: err.Error(),
+			})
+		case errors.Is(err, errs.ErrDBOperation):
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Database operation failed",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Internal server error",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
 func (h *DjnHandler) PatchStat(c *gin.Context) {
 	regionID := auth.GetRegionIDFromContext(c)
 	if regionID == 0 {
@@ -98,12 +154,30 @@ func (h *DjnHandler) PatchStat(c *gin.Context) {
 
 	log.Printf("Received data for region %d, name %s: %+v", regionID, obj.Name, obj)
 
-	if err := h.serv.PatchStat(regionID, obj); err != nil {
+	// Изменение возможно только для версии отчета, которую видел клиент
+	updated, err := h.serv.PatchStat(regionID, obj, actorFromContext(c), c.GetHeader("If-Match"))
+	if err != nil {
 		switch {
+		case errors.Is(err, errs.ErrBadRequest):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: err.Error(),
+			})
 		case errors.Is(err, errs.ErrNotFound):
 			c.JSON(http.StatusNotFound, models.ErrorResponse{
 				Error: "Record not found",
 			})
+		case errors.Is(err, errs.ErrPreconditionRequired):
+			c.JSON(http.StatusPreconditionRequired, models.ErrorResponse{
+				Error: err.Error(),
+			})
+		case errors.Is(err, errs.ErrPreconditionFailed):
+			c.JSON(http.StatusPreconditionFailed, models.ErrorResponse{
+				Error: err.Error(),
+			})
+		case errors.Is(err, errs.ErrConflict):
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error: err.Error(),
+			})
 		case errors.Is(err, errs.ErrDBOperation):
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 				Error: "Database operation failed",
@@ -116,9 +190,11 @@ func (h *DjnHandler) PatchStat(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", updated.ETag())
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Data updated successfully",
 		"status":  "success",
+		"version": updated.Version,
 	})
 }
 
@@ -151,13 +227,21 @@ func (h *DjnHandler) PostStat(c *gin.Context) {
 	// Автоматически устанавливаем имя пользователя из сессии
 	obj.Name = username
 	obj.RegionID = regionID
-	obj.Date = time.Now().Format("2006-01-02")
 
-	if err := h.serv.PostStat(obj); err != nil {
+	// Без даты отчет подается за сегодня, прошедшие дни проверяются политикой дозаполнения
+	if obj.Date == "" {
+		obj.Date = time.Now().Format("2006-01-02")
+	}
+
+	if err := h.serv.PostStat(obj, actorFromContext(c)); err != nil {
 		switch {
+		case errors.Is(err, errs.ErrBadRequest):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: err.Error(),
+			})
 		case errors.Is(err, errs.ErrUniqueName):
 			c.JSON(http.StatusConflict, models.ErrorResponse{
-				Error: "You have already submitted data for today",
+				Error: "You have already submitted data for " + obj.Date,
 			})
 		case errors.Is(err, errs.ErrDBOperation):
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
@@ -216,6 +300,11 @@ func (h *DjnHandler) GetStatByRegion(c *gin.Context) {
 		return
 	}
 
+	// Для единственного отчета пользователя отдаем ETag для последующего PATCH
+	if len(stats) == 1 {
+		c.Header("ETag", stats[0].ETag())
+	}
+
 	c.JSON(http.StatusOK, stats)
 }
 
@@ -228,74 +317,192 @@ func (h *DjnHandler) GetInfo(c *gin.Context) {
 		return
 	}
 
-	regionStat, regionQuantity := summa.GetStatsForRegion(regionID)
+	date := c.DefaultQuery("date", time.Now().Format("2006-01-02"))
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid date format. Use YYYY-MM-DD",
+		})
+		return
+	}
+
+	products, err := h.products.GetProducts(false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Database operation failed",
+		})
+		return
+	}
+
+	defs, err := h.metrics.GetRegionMetrics(regionID, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Database operation failed",
+		})
+		return
+	}
+
+	regionStat, regionQuantity, err := h.serv.GetRegionTotals(regionID, date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Database operation failed",
+		})
+		return
+	}
+	totals := productTotals(products, regionStat)
+	metricTotals := metricTotals(defs, regionStat)
 
 	response := gin.H{
 		// Основная информация
 		"total_reports": regionQuantity,
 		"region_id":     regionID,
+		"date":          date,
 
-		// Семечка
-		"total_seed_plan": regionStat.SeedPlan,
-		"total_seed_fact": regionStat.SeedFact,
-		"total_seed_dif":  regionStat.SeedDif,
-
-		// Тыква
-		"total_pumpkin_plan": regionStat.PumpkinPlan,
-		"total_pumpkin_fact": regionStat.PumpkinFact,
-		"total_pumpkin_dif":  regionStat.PumpkinDif,
-
-		// Арахис
-		"total_peanut_plan": regionStat.PeanutPlan,
-		"total_peanut_fact": regionStat.PeanutFact,
-		"total_peanut_dif":  regionStat.PeanutDif,
-
-		// Дополнительные метрики
-		"total_akb1":         regionStat.AKB1,
-		"total_akb2":         regionStat.AKB2,
-		"total_newtt":        regionStat.NewTT,
-		"total_mix":          regionStat.Mix,
-		"total_npone":        regionStat.NpOne,
-		"total_set_shelving": regionStat.SetShel,
-		"total_dmp":          regionStat.DMP,
-		"total_top_five":     regionStat.TopFive,
-		"total_news":         regionStat.News,
+		// Итоги по продуктам каталога и счетчикам региона
+		"products": totals,
+		"metrics":  metricTotals,
+	}
+
+	// Плоские поля total_<code>_plan/_fact/_dif и total_<code> для совместимости со страницами статистики
+	for _, p := range totals {
+		response["total_"+p.Code+"_plan"] = p.Plan
+		response["total_"+p.Code+"_fact"] = p.Fact
+		response["total_"+p.Code+"_dif"] = p.Dif
+	}
+	for _, m := range metricTotals {
+		response["total_"+m.Code] = m.Value
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-func (h *DjnHandler) GetAllRegionalStats(c *gin.Context) {
-	allStats := summa.GetAllRegionalStats()
-	allQuantities := summa.GetAllQuantities()
-
-	response := make(map[string]interface{})
-
-	for regionID, stat := range allStats {
-		quantity := allQuantities[regionID]
-		response[fmt.Sprintf("region_%d", regionID)] = gin.H{
-			"region_id":     regionID,
-			"total_reports": quantity,
-			"seed_plan":     stat.SeedPlan,
-			"seed_fact":     stat.SeedFact,
-			"seed_dif":      stat.SeedDif,
-			"pumpkin_plan":  stat.PumpkinPlan,
-			"pumpkin_fact":  stat.PumpkinFact,
-			"pumpkin_dif":   stat.PumpkinDif,
-			"peanut_plan":   stat.PeanutPlan,
-			"peanut_fact":   stat.PeanutFact,
-			"peanut_dif":    stat.PeanutDif,
-			"akb1":          stat.AKB1,
-			"akb2":          stat.AKB2,
-			"newtt":         stat.NewTT,
-			"mix":           stat.Mix,
-			"npone":         stat.NpOne,
-			"set_shelving":  stat.SetShel,
-			"dmp":           stat.DMP,
-			"top_five":      stat.TopFive,
-			"news":          stat.News,
+func (h *DjnHandler) RebuildStats(c *gin.Context) {
+	if err := h.serv.RebuildStats(); err != nil {
+		switch {
+		case errors.Is(err, errs.ErrDBOperation):
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Database operation failed",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Internal server error",
+			})
 		}
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Statistics rebuilt successfully",
+		"status":  "success",
+	})
+}
+
+// Сверить агрегаты с БД без полной пересборки
+func (h *DjnHandler) ReconcileStats(c *gin.Context) {
+	repaired, err := h.serv.ReconcileStats()
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrDBOperation):
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Database operation failed",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Internal server error",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Statistics reconciled successfully",
+		"status":   "success",
+		"repaired": repaired,
+	})
+}
+
+// Итог по продукту каталога
+type ProductTotal struct {
+	Code string  `json:"code"`
+	Name string  `json:"name"`
+	Plan float64 `json:"plan"`
+	Fact float64 `json:"fact"`
+	Dif  float64 `json:"dif"`
+}
+
+// Итоги агрегата в порядке каталога. Активные продукты выводятся всегда,
+// отключенные - только если по ним есть значения
+func productTotals(catalog []models.Product, stat models.StatDaily) []ProductTotal {
+	totals := make([]ProductTotal, 0, len(catalog))
+
+	for _, product := range catalog {
+		p := stat.Product(product.Code)
+		if p == nil && !product.Active {
+			continue
+		}
+
+		total := ProductTotal{Code: product.Code, Name: product.Name}
+		if p != nil {
+			total.Plan = p.Plan
+			total.Fact = p.Fact
+			total.Dif = p.Dif
+		}
+		totals = append(totals, total)
+	}
+
+	return totals
+}
+
+// Итог по счетчику региона
+type MetricTotal struct {
+	Code  string `json:"code"`
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value int    `json:"value"`
+}
+
+// Итоги счетчиков в порядке определений региона. Активные счетчики выводятся всегда,
+// отключенные - только если по ним есть значения
+func metricTotals(defs []models.MetricDefinition, stat models.StatDaily) []MetricTotal {
+	totals := make([]MetricTotal, 0, len(defs))
+
+	for _, def := range defs {
+		m := stat.Metric(def.Code)
+		if m == nil && !def.Active {
+			continue
+		}
+
+		total := MetricTotal{Code: def.Code, Name: def.Name, Type: def.Type}
+		if m != nil {
+			total.Value = m.Value
+		}
+		totals = append(totals, total)
+	}
+
+	return totals
+}
+
+func (h *DjnHandler) GetRetention(c *gin.Context) {
+	regionID := auth.GetRegionIDFromContext(c)
+	if regionID == 0 {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error: "Region is not defined",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.serv.GetRetention(regionID))
+}
+
+// Автор изменения для истории ревизий
+func actorFromContext(c *gin.Context) models.Actor {
+	return models.Actor{
+		Username: c.GetString("username"),
+		Role:     auth.GetUserRoleFromContext(c),
+		IP:       c.ClientIP(),
+	}
+}
+
+// Окно подачи отчетов задним числом для текущего пользователя
+func (h *DjnHandler) GetBackfill(c *gin.Context) {
+	c.JSON(http.StatusOK, h.serv.GetBackfill(auth.GetUserRoleFromContext(c)))
 }
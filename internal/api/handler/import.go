@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Максимальный размер файла импорта отчетов
+const maxImportFileSize = 5 << 20
+
+// Импорт отчетов региона из CSV или XLSX (multipart, поле file; ?region_id&dry_run=true).
+// Без dry_run отчеты сохраняются, только если ни в одной строке нет ошибок
+func (h *DjnHandler) ImportStats(c *gin.Context) {
+	regionID, err := strconv.ParseUint(c.Query("region_id"), 10, 64)
+	if err != nil || regionID == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid region ID",
+		})
+		return
+	}
+	dryRun := c.Query("dry_run") == "true"
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "File is required",
+		})
+		return
+	}
+	if header.Size > maxImportFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{
+			Error: "File is too large",
+		})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Failed to read file",
+		})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Failed to read file",
+		})
+		return
+	}
+
+	result, err := h.serv.ImportStats(uint(regionID), header.Filename, data, actorFromContext(c), dryRun)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrBadRequest):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: err.Error(),
+			})
+		case errors.Is(err, errs.ErrUniqueName):
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error: err.Error(),
+			})
+		case errors.Is(err, errs.ErrDBOperation):
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Database operation failed",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Internal server error",
+			})
+		}
+		return
+	}
+
+	if !dryRun && len(result.Errors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
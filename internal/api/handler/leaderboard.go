@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Wladim1r/statcounter/internal/api/service"
+	"github.com/Wladim1r/statcounter/internal/auth"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type LeaderboardHandler struct {
+	serv service.LeaderboardService
+}
+
+func NewLeaderboardHandler(serv service.LeaderboardService) LeaderboardHandler {
+	return LeaderboardHandler{serv: serv}
+}
+
+// Рейтинг пользователей своего региона
+// (?period=day|week|month&date&by=percent|fact|score&product&min_reports)
+func (h *LeaderboardHandler) GetLeaderboard(c *gin.Context) {
+	regionID := auth.GetRegionIDFromContext(c)
+	if regionID == 0 {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error: "Region is not defined",
+		})
+		return
+	}
+
+	h.writeLeaderboard(c, regionID)
+}
+
+// Рейтинг по всем регионам или по одному (?region_id), параметры как у GetLeaderboard
+func (h *LeaderboardHandler) GetCompanyLeaderboard(c *gin.Context) {
+	regionID, err := strconv.ParseUint(c.DefaultQuery("region_id", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid region ID",
+		})
+		return
+	}
+
+	h.writeLeaderboard(c, uint(regionID))
+}
+
+func (h *LeaderboardHandler) writeLeaderboard(c *gin.Context, regionID uint) {
+	minReports, err := strconv.Atoi(c.DefaultQuery("min_reports", "1"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid min_reports",
+		})
+		return
+	}
+
+	leaderboard, err := h.serv.GetLeaderboard(service.LeaderboardQuery{
+		RegionID:   regionID,
+		Period:     c.Query("period"),
+		Date:       c.Query("date"),
+		By:         c.Query("by"),
+		Product:    c.Query("product"),
+		MinReports: minReports,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrBadRequest):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: err.Error(),
+			})
+		case errors.Is(err, errs.ErrDBOperation):
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Database operation failed",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Internal server error",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, leaderboard)
+}
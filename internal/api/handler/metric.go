@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Wladim1r/statcounter/internal/api/service"
+	"github.com/Wladim1r/statcounter/internal/auth"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type MetricHandler struct {
+	serv service.MetricService
+}
+
+func NewMetricHandler(serv service.MetricService) MetricHandler {
+	return MetricHandler{serv: serv}
+}
+
+// Активные счетчики региона текущего пользователя для форм ввода и таблиц
+func (h *MetricHandler) GetRegionMetrics(c *gin.Context) {
+	regionID := auth.GetRegionIDFromContext(c)
+	if regionID == 0 {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error: "Region is not defined",
+		})
+		return
+	}
+
+	defs, err := h.serv.GetRegionMetrics(regionID, true)
+	if err != nil {
+		writeMetricError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, defs)
+}
+
+// Все определения счетчиков; с region_id - действующие для региона
+func (h *MetricHandler) GetMetrics(c *gin.Context) {
+	var (
+		defs []models.MetricDefinition
+		err  error
+	)
+
+	if regionParam := c.Query("region_id"); regionParam != "" {
+		regionID, parseErr := strconv.ParseUint(regionParam, 10, 64)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Invalid region ID",
+			})
+			return
+		}
+		defs, err = h.serv.GetRegionMetrics(uint(regionID), false)
+	} else {
+		defs, err = h.serv.GetAllMetrics()
+	}
+
+	if err != nil {
+		writeMetricError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, defs)
+}
+
+func (h *MetricHandler) CreateMetric(c *gin.Context) {
+	var req service.MetricRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid Body Request",
+		})
+		return
+	}
+
+	def, err := h.serv.CreateMetric(req)
+	if err != nil {
+		writeMetricError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, def)
+}
+
+func (h *MetricHandler) UpdateMetric(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid metric ID",
+		})
+		return
+	}
+
+	var req service.MetricRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid Body Request",
+		})
+		return
+	}
+
+	def, err := h.serv.UpdateMetric(uint(id), req)
+	if err != nil {
+		writeMetricError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, def)
+}
+
+func (h *MetricHandler) DeleteMetric(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid metric ID",
+		})
+		return
+	}
+
+	if err := h.serv.DeleteMetric(uint(id)); err != nil {
+		writeMetricError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Metric deleted successfully",
+		"status":  "success",
+	})
+}
+
+func writeMetricError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errs.ErrBadRequest):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Metric not found",
+		})
+	case errors.Is(err, errs.ErrUniqueName):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "Metric code already exists in this region",
+		})
+	case errors.Is(err, errs.ErrConflict):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "Metric is used in reports, deactivate it instead",
+		})
+	case errors.Is(err, errs.ErrDBOperation):
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Database operation failed",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Internal server error",
+		})
+	}
+}
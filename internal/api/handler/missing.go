@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/service"
+	"github.com/Wladim1r/statcounter/internal/auth"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type MissingHandler struct {
+	serv service.MissingService
+}
+
+func NewMissingHandler(serv service.MissingService) MissingHandler {
+	return MissingHandler{serv: serv}
+}
+
+// Несданные отчеты своего региона (?date=YYYY-MM-DD, по умолчанию сегодня)
+func (h *MissingHandler) GetMissing(c *gin.Context) {
+	regionID := auth.GetRegionIDFromContext(c)
+	if regionID == 0 {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error: "Region is not defined",
+		})
+		return
+	}
+
+	missing, err := h.serv.GetMissing(regionID, c.Query("date"))
+	if err != nil {
+		writeMissingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, missing)
+}
+
+// Несданные отчеты любого региона (?region_id&date)
+func (h *MissingHandler) GetRegionMissing(c *gin.Context) {
+	regionID, err := strconv.ParseUint(c.Query("region_id"), 10, 64)
+	if err != nil || regionID == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid region ID",
+		})
+		return
+	}
+
+	missing, err := h.serv.GetMissing(uint(regionID), c.Query("date"))
+	if err != nil {
+		writeMissingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, missing)
+}
+
+// Разослать напоминания региона сейчас, не дожидаясь планировщика (?region_id)
+func (h *MissingHandler) SendReminders(c *gin.Context) {
+	regionID, err := strconv.ParseUint(c.Query("region_id"), 10, 64)
+	if err != nil || regionID == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid region ID",
+		})
+		return
+	}
+
+	result, err := h.serv.SendReminders(c.Request.Context(), uint(regionID), time.Now())
+	if err != nil {
+		writeMissingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func writeMissingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errs.ErrBadRequest):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrConflict):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrUnavailable):
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrDBOperation):
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Database operation failed",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Internal server error",
+		})
+	}
+}
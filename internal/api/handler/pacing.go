@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/service"
+	"github.com/Wladim1r/statcounter/internal/auth"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type PacingHandler struct {
+	serv service.PacingService
+}
+
+func NewPacingHandler(serv service.PacingService) PacingHandler {
+	return PacingHandler{serv: serv}
+}
+
+// Темп региона пользователя за месяц (?month=YYYY-MM, ?user=true - только свои отчеты)
+func (h *PacingHandler) GetPacing(c *gin.Context) {
+	regionID := auth.GetRegionIDFromContext(c)
+	if regionID == 0 {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error: "Region is not defined",
+		})
+		return
+	}
+
+	var username string
+	if c.Query("user") == "true" {
+		username = c.GetString("username")
+	}
+
+	pacing, err := h.serv.GetPacing(
+		regionID,
+		username,
+		c.DefaultQuery("month", time.Now().Format("2006-01")),
+	)
+	if err != nil {
+		writePacingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pacing)
+}
+
+// Темп любого региона или пользователя (?region_id&month=YYYY-MM&name)
+func (h *PacingHandler) GetRegionPacing(c *gin.Context) {
+	regionID, err := strconv.ParseUint(c.Query("region_id"), 10, 64)
+	if err != nil || regionID == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid region ID",
+		})
+		return
+	}
+
+	pacing, err := h.serv.GetPacing(
+		uint(regionID),
+		c.Query("name"),
+		c.DefaultQuery("month", time.Now().Format("2006-01")),
+	)
+	if err != nil {
+		writePacingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pacing)
+}
+
+func writePacingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errs.ErrBadRequest):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrDBOperation):
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Database operation failed",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Internal server error",
+		})
+	}
+}
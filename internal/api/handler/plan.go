@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/service"
+	"github.com/Wladim1r/statcounter/internal/auth"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type PlanHandler struct {
+	serv service.PlanService
+}
+
+func NewPlanHandler(serv service.PlanService) PlanHandler {
+	return PlanHandler{serv: serv}
+}
+
+// План текущего пользователя на день (?date, по умолчанию сегодня)
+func (h *PlanHandler) GetDailyPlan(c *gin.Context) {
+	regionID := auth.GetRegionIDFromContext(c)
+	if regionID == 0 {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error: "Region is not defined",
+		})
+		return
+	}
+
+	date := c.DefaultQuery("date", time.Now().Format("2006-01-02"))
+
+	plan, err := h.serv.GetDailyPlan(regionID, c.GetString("username"), date)
+	if err != nil {
+		writePlanError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// Цели региона за месяц (?region_id&month=YYYY-MM)
+func (h *PlanHandler) GetTargets(c *gin.Context) {
+	regionID, err := strconv.ParseUint(c.Query("region_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid region ID",
+		})
+		return
+	}
+
+	month := c.DefaultQuery("month", time.Now().Format("2006-01"))
+
+	targets, err := h.serv.GetTargets(uint(regionID), month)
+	if err != nil {
+		writePlanError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, targets)
+}
+
+func (h *PlanHandler) SaveTarget(c *gin.Context) {
+	var req service.PlanTargetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid Body Request",
+		})
+		return
+	}
+
+	target, err := h.serv.SaveTarget(req, c.GetString("username"))
+	if err != nil {
+		writePlanError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, target)
+}
+
+func (h *PlanHandler) DeleteTarget(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid target ID",
+		})
+		return
+	}
+
+	if err := h.serv.DeleteTarget(uint(id)); err != nil {
+		writePlanError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Plan target deleted successfully",
+		"status":  "success",
+	})
+}
+
+func writePlanError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errs.ErrBadRequest):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Plan target not found",
+		})
+	case errors.Is(err, errs.ErrDBOperation):
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Database operation failed",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Internal server error",
+		})
+	}
+}
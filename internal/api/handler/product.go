@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Wladim1r/statcounter/internal/api/service"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type ProductHandler struct {
+	serv service.ProductService
+}
+
+func NewProductHandler(serv service.ProductService) ProductHandler {
+	return ProductHandler{serv: serv}
+}
+
+// Активные продукты для форм ввода и таблиц
+func (h *ProductHandler) GetActiveProducts(c *gin.Context) {
+	products, err := h.serv.GetProducts(true)
+	if err != nil {
+		writeProductError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, products)
+}
+
+func (h *ProductHandler) GetProducts(c *gin.Context) {
+	products, err := h.serv.GetProducts(false)
+	if err != nil {
+		writeProductError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, products)
+}
+
+func (h *ProductHandler) CreateProduct(c *gin.Context) {
+	var req service.ProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid Body Request",
+		})
+		return
+	}
+
+	product, err := h.serv.CreateProduct(req)
+	if err != nil {
+		writeProductError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, product)
+}
+
+func (h *ProductHandler) UpdateProduct(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid product ID",
+		})
+		return
+	}
+
+	var req service.ProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid Body Request",
+		})
+		return
+	}
+
+	product, err := h.serv.UpdateProduct(uint(id), req)
+	if err != nil {
+		writeProductError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+func (h *ProductHandler) DeleteProduct(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid product ID",
+		})
+		return
+	}
+
+	if err := h.serv.DeleteProduct(uint(id)); err != nil {
+		writeProductError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product deleted successfully",
+		"status":  "success",
+	})
+}
+
+func writeProductError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errs.ErrBadRequest):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Product not found",
+		})
+	case errors.Is(err, errs.ErrUniqueName):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "Product code already exists",
+		})
+	case errors.Is(err, errs.ErrConflict):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "Product is used in reports, deactivate it instead",
+		})
+	case errors.Is(err, errs.ErrDBOperation):
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Database operation failed",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Internal server error",
+		})
+	}
+}
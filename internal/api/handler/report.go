@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Создание отчета администратором за любого пользователя и дату
+func (h *DjnHandler) CreateReport(c *gin.Context) {
+	var obj models.StatDaily
+	if err := c.ShouldBindJSON(&obj); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid Body Request",
+		})
+		return
+	}
+
+	if err := h.serv.CreateStatFor(obj, actorFromContext(c)); err != nil {
+		writeReportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Report created successfully",
+		"status":  "success",
+	})
+}
+
+func (h *DjnHandler) UpdateReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid report ID",
+		})
+		return
+	}
+
+	var obj models.StatDaily
+	if err := c.ShouldBindJSON(&obj); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid Body Request",
+		})
+		return
+	}
+
+	updated, err := h.serv.UpdateStatByID(uint(id), obj, actorFromContext(c), c.GetHeader("If-Match"))
+	if err != nil {
+		writeReportError(c, err)
+		return
+	}
+
+	c.Header("ETag", updated.ETag())
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Report updated successfully",
+		"status":  "success",
+		"version": updated.Version,
+	})
+}
+
+func (h *DjnHandler) DeleteReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid report ID",
+		})
+		return
+	}
+
+	if err := h.serv.DeleteStatByID(uint(id), actorFromContext(c)); err != nil {
+		writeReportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Report deleted successfully",
+		"status":  "success",
+	})
+}
+
+func writeReportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errs.ErrBadRequest):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Report not found",
+		})
+	case errors.Is(err, errs.ErrUniqueName), errors.Is(err, errs.ErrConflict):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrPreconditionRequired):
+		c.JSON(http.StatusPreconditionRequired, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrPreconditionFailed):
+		c.JSON(http.StatusPreconditionFailed, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrDBOperation):
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Database operation failed",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Internal server error",
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Wladim1r/statcounter/internal/api/repository"
+	"github.com/Wladim1r/statcounter/internal/api/service"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type RevisionHandler struct {
+	serv service.RevisionService
+}
+
+func NewRevisionHandler(serv service.RevisionService) RevisionHandler {
+	return RevisionHandler{serv: serv}
+}
+
+// История отчета по его ID
+func (h *RevisionHandler) GetReportHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid report ID",
+		})
+		return
+	}
+
+	revisions, err := h.serv.GetRevisions(repository.RevisionFilter{ReportID: uint(id)})
+	if err != nil {
+		writeRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// Поиск ревизий по региону, пользователю и дате, в том числе удаленных отчетов
+func (h *RevisionHandler) GetRevisions(c *gin.Context) {
+	var filter repository.RevisionFilter
+
+	if value := c.Query("region_id"); value != "" {
+		regionID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Invalid region ID",
+			})
+			return
+		}
+		filter.RegionID = uint(regionID)
+	}
+	filter.Name = c.Query("name")
+	filter.Date = c.Query("date")
+
+	revisions, err := h.serv.GetRevisions(filter)
+	if err != nil {
+		writeRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+func (h *RevisionHandler) RestoreRevision(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid revision ID",
+		})
+		return
+	}
+
+	if err := h.serv.RestoreRevision(uint(id), actorFromContext(c)); err != nil {
+		writeRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Report restored successfully",
+		"status":  "success",
+	})
+}
+
+func writeRevisionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errs.ErrBadRequest):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Revision not found",
+		})
+	case errors.Is(err, errs.ErrConflict):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrDBOperation):
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Database operation failed",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Internal server error",
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Wladim1r/statcounter/internal/api/service"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type ScoringHandler struct {
+	serv service.ScoringService
+}
+
+func NewScoringHandler(serv service.ScoringService) ScoringHandler {
+	return ScoringHandler{serv: serv}
+}
+
+func (h *ScoringHandler) GetFormulas(c *gin.Context) {
+	formulas, err := h.serv.GetFormulas()
+	if err != nil {
+		writeScoringError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, formulas)
+}
+
+// Создать или заменить формулу региона
+func (h *ScoringHandler) SaveFormula(c *gin.Context) {
+	var req service.ScoringFormulaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid Body Request",
+		})
+		return
+	}
+
+	formula, err := h.serv.SaveFormula(req, c.GetString("username"))
+	if err != nil {
+		writeScoringError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, formula)
+}
+
+func (h *ScoringHandler) DeleteFormula(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid formula ID",
+		})
+		return
+	}
+
+	if err := h.serv.DeleteFormula(uint(id)); err != nil {
+		writeScoringError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Scoring formula deleted successfully",
+		"status":  "success",
+	})
+}
+
+func writeScoringError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errs.ErrBadRequest):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errs.ErrNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Scoring formula not found",
+		})
+	case errors.Is(err, errs.ErrDBOperation):
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Database operation failed",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Internal server error",
+		})
+	}
+}
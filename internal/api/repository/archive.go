@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/lib/summa"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ArchiveRepo interface {
+	// Сводки региона за месяц (month - первый день месяца YYYY-MM-01)
+	GetMonthlySummaries(regionID uint, month string) ([]models.MonthlySummary, error)
+}
+
+type archiveRepo struct {
+	db *gorm.DB
+}
+
+func NewArchiveRepo(db *gorm.DB) ArchiveRepo {
+	return &archiveRepo{db: db}
+}
+
+func (r *archiveRepo) GetMonthlySummaries(
+	regionID uint,
+	month string,
+) ([]models.MonthlySummary, error) {
+	var summaries []models.MonthlySummary
+
+	if err := r.db.Preload("Products").Preload("Metrics").
+		Where("region_id = ? AND month = ?", regionID, month).
+		Order("name").
+		Find(&summaries).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return summaries, nil
+}
+
+// Ключ месячной сводки; пустое имя - сводка по региону
+type summaryKey struct {
+	month    string
+	regionID uint
+	name     string
+}
+
+// Добавить удаляемые ежедневные отчеты в месячные сводки пользователей и регионов.
+// Вызывается в той же транзакции, что и удаление, поэтому каждый отчет попадает в архив ровно один раз
+func archiveStats(tx *gorm.DB, stats []models.StatDaily) error {
+	totals := make(map[summaryKey]models.StatDaily)
+	reports := make(map[summaryKey]int)
+	var keys []summaryKey
+
+	for _, stat := range stats {
+		month := stat.Date[:len("2006-01")] + "-01"
+
+		for _, name := range []string{stat.Name, ""} {
+			key := summaryKey{month: month, regionID: stat.RegionID, name: name}
+			if _, ok := totals[key]; !ok {
+				keys = append(keys, key)
+			}
+			totals[key] = summa.Sum(totals[key], stat)
+			reports[key]++
+		}
+	}
+
+	for _, key := range keys {
+		var summary models.MonthlySummary
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Products").Preload("Metrics").
+			Where("month = ? AND region_id = ? AND name = ?", key.month, key.regionID, key.name).
+			First(&summary).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			summary = models.MonthlySummary{Month: key.month, RegionID: key.regionID, Name: key.name}
+			if err := tx.Omit(clause.Associations).Create(&summary).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		}
+
+		summary.Reports += reports[key]
+		summary.SetStat(summa.Sum(summary.Stat(), totals[key]))
+
+		if err := tx.Model(&summary).Update("reports", summary.Reports).Error; err != nil {
+			return err
+		}
+
+		for _, p := range summary.Products {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "summary_id"}, {Name: "code"}},
+				DoUpdates: clause.AssignmentColumns([]string{"plan", "fact", "dif"}),
+			}).Create(&p).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, m := range summary.Metrics {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "summary_id"}, {Name: "code"}},
+				DoUpdates: clause.AssignmentColumns([]string{"value"}),
+			}).Create(&m).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BonusRepo interface {
+	GetBonusRules() ([]models.BonusRule, error)
+	// Активные правила региона и общие (RegionID 0)
+	GetRegionBonusRules(regionID uint) ([]models.BonusRule, error)
+	GetBonusRuleByID(id uint) (*models.BonusRule, error)
+	CreateBonusRule(rule *models.BonusRule) error
+	UpdateBonusRule(rule *models.BonusRule) error
+	DeleteBonusRule(id uint) error
+
+	// Расчеты за месяц (YYYY-MM-01) без начислений, пустой месяц - все расчеты
+	GetBonusRuns(month string) ([]models.BonusRun, error)
+	GetBonusRunByID(id uint) (*models.BonusRun, error)
+	// Сохранить расчет с начислениями, заменяя черновик за тот же месяц и регион.
+	// Утвержденный расчет не изменяется (ErrConflict)
+	SaveBonusRun(run *models.BonusRun) error
+	LockBonusRun(run *models.BonusRun) error
+	DeleteBonusRun(id uint) error
+}
+
+type bonusRepo struct {
+	db *gorm.DB
+}
+
+func NewBonusRepo(db *gorm.DB) BonusRepo {
+	return &bonusRepo{db: db}
+}
+
+func (r *bonusRepo) GetBonusRules() ([]models.BonusRule, error) {
+	var rules []models.BonusRule
+
+	if err := r.db.Order("region_id, id").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return rules, nil
+}
+
+func (r *bonusRepo) GetRegionBonusRules(regionID uint) ([]models.BonusRule, error) {
+	var rules []models.BonusRule
+
+	if err := r.db.Where("region_id IN ? AND active = ?", []uint{0, regionID}, true).
+		Order("region_id, id").
+		Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return rules, nil
+}
+
+func (r *bonusRepo) GetBonusRuleByID(id uint) (*models.BonusRule, error) {
+	var rule models.BonusRule
+	if err := r.db.First(&rule, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return &rule, nil
+}
+
+func (r *bonusRepo) CreateBonusRule(rule *models.BonusRule) error {
+	if err := r.db.Create(rule).Error; err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return nil
+}
+
+func (r *bonusRepo) UpdateBonusRule(rule *models.BonusRule) error {
+	result := r.db.Model(&models.BonusRule{ID: rule.ID}).Select("*").Omit("id").Updates(rule)
+	if result.Error != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *bonusRepo) DeleteBonusRule(id uint) error {
+	result := r.db.Delete(&models.BonusRule{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *bonusRepo) GetBonusRuns(month string) ([]models.BonusRun, error) {
+	var runs []models.BonusRun
+
+	query := r.db.Order("month DESC, region_id")
+	if month != "" {
+		query = query.Where("month = ?", month)
+	}
+
+	if err := query.Find(&runs).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	for i := range runs {
+		runs[i].Month = normalizeDate(runs[i].Month)
+	}
+
+	return runs, nil
+}
+
+func (r *bonusRepo) GetBonusRunByID(id uint) (*models.BonusRun, error) {
+	var run models.BonusRun
+	if err := r.db.Preload("Payouts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name")
+	}).First(&run, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	run.Month = normalizeDate(run.Month)
+
+	return &run, nil
+}
+
+func (r *bonusRepo) SaveBonusRun(run *models.BonusRun) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.BonusRun
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("month = ? AND region_id = ?", run.Month, run.RegionID).
+			First(&existing).Error
+
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return tx.Create(run).Error
+		case err != nil:
+			return err
+		case existing.Status == models.BonusRunLocked:
+			return fmt.Errorf("%w: bonus run for this month is locked", errs.ErrConflict)
+		}
+
+		// Черновик пересчитывается целиком
+		if err := tx.Where("run_id = ?", existing.ID).Delete(&models.BonusPayout{}).Error; err != nil {
+			return err
+		}
+
+		run.ID = existing.ID
+		for i := range run.Payouts {
+			run.Payouts[i].ID = 0
+			run.Payouts[i].RunID = run.ID
+		}
+
+		if err := tx.Omit(clause.Associations).Save(run).Error; err != nil {
+			return err
+		}
+		if len(run.Payouts) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(run.Payouts, 200).Error
+	})
+	if err != nil {
+		if errors.Is(err, errs.ErrConflict) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return nil
+}
+
+func (r *bonusRepo) LockBonusRun(run *models.BonusRun) error {
+	result := r.db.Model(&models.BonusRun{}).
+		Where("id = ? AND status = ?", run.ID, models.BonusRunDraft).
+		Updates(map[string]interface{}{
+			"status":    models.BonusRunLocked,
+			"locked_by": run.LockedBy,
+			"locked_at": run.LockedAt,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		if _, err := r.GetBonusRunByID(run.ID); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: bonus run is already locked", errs.ErrConflict)
+	}
+
+	return nil
+}
+
+func (r *bonusRepo) DeleteBonusRun(id uint) error {
+	result := r.db.Where("status = ?", models.BonusRunDraft).Delete(&models.BonusRun{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		if _, err := r.GetBonusRunByID(id); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: locked bonus run cannot be deleted", errs.ErrConflict)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CalendarRepo interface {
+	// Рабочие недели региона и общая (RegionID 0), если заданы
+	GetWorkWeeks(regionID uint) ([]models.WorkWeek, error)
+	SaveWorkWeek(week *models.WorkWeek) error
+	// Особые дни региона и общие за период включительно
+	GetCalendarDays(regionID uint, from string, to string) ([]models.CalendarDay, error)
+	// Сохранить дни в одной транзакции, заменяя существующие на те же даты
+	SaveCalendarDays(days []models.CalendarDay) error
+	DeleteCalendarDay(id uint) error
+}
+
+type calendarRepo struct {
+	db *gorm.DB
+}
+
+func NewCalendarRepo(db *gorm.DB) CalendarRepo {
+	return &calendarRepo{db: db}
+}
+
+func (r *calendarRepo) GetWorkWeeks(regionID uint) ([]models.WorkWeek, error) {
+	var weeks []models.WorkWeek
+
+	if err := r.db.Where("region_id IN ?", []uint{0, regionID}).
+		Order("region_id").
+		Find(&weeks).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return weeks, nil
+}
+
+func (r *calendarRepo) SaveWorkWeek(week *models.WorkWeek) error {
+	if err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "region_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"weekends", "updated_by", "updated_at"}),
+	}).Create(week).Error; err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	if err := r.db.Where("region_id = ?", week.RegionID).First(week).Error; err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return nil
+}
+
+func (r *calendarRepo) GetCalendarDays(
+	regionID uint,
+	from string,
+	to string,
+) ([]models.CalendarDay, error) {
+	var days []models.CalendarDay
+
+	if err := r.db.Where("region_id IN ? AND date BETWEEN ? AND ?", []uint{0, regionID}, from, to).
+		Order("date, region_id").
+		Find(&days).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	for i := range days {
+		days[i].Date = normalizeDate(days[i].Date)
+	}
+
+	return days, nil
+}
+
+func (r *calendarRepo) SaveCalendarDays(days []models.CalendarDay) error {
+	if len(days) == 0 {
+		return nil
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "region_id"}, {Name: "date"}},
+			DoUpdates: clause.AssignmentColumns([]string{"working", "name", "updated_by", "updated_at"}),
+		}).CreateInBatches(days, 200).Error
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return nil
+}
+
+func (r *calendarRepo) DeleteCalendarDay(id uint) error {
+	result := r.db.Delete(&models.CalendarDay{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"gorm.io/gorm"
+)
+
+type MetricRepo interface {
+	// Определения счетчиков региона вместе с общими (region_id = 0)
+	GetMetricDefinitions(regionID uint, activeOnly bool) ([]models.MetricDefinition, error)
+	GetAllMetricDefinitions() ([]models.MetricDefinition, error)
+	GetMetricDefinitionByID(id uint) (*models.MetricDefinition, error)
+	CreateMetricDefinition(def *models.MetricDefinition) error
+	UpdateMetricDefinition(id uint, updates map[string]interface{}) (*models.MetricDefinition, error)
+	DeleteMetricDefinition(id uint) error
+}
+
+type metricRepo struct {
+	db *gorm.DB
+}
+
+func NewMetricRepo(db *gorm.DB) MetricRepo {
+	return &metricRepo{db: db}
+}
+
+func (r *metricRepo) GetMetricDefinitions(
+	regionID uint,
+	activeOnly bool,
+) ([]models.MetricDefinition, error) {
+	var defs []models.MetricDefinition
+
+	query := r.db.Where("region_id IN ?", []uint{0, regionID}).Order("position, id")
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+
+	if err := query.Find(&defs).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return defs, nil
+}
+
+func (r *metricRepo) GetAllMetricDefinitions() ([]models.MetricDefinition, error) {
+	var defs []models.MetricDefinition
+	if err := r.db.Order("region_id, position, id").Find(&defs).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return defs, nil
+}
+
+func (r *metricRepo) GetMetricDefinitionByID(id uint) (*models.MetricDefinition, error) {
+	var def models.MetricDefinition
+	if err := r.db.First(&def, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return &def, nil
+}
+
+func (r *metricRepo) CreateMetricDefinition(def *models.MetricDefinition) error {
+	if err := r.db.Create(def).Error; err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "duplicate key") {
+			return fmt.Errorf("%w: metric code %s already exists", errs.ErrUniqueName, def.Code)
+		}
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return nil
+}
+
+func (r *metricRepo) UpdateMetricDefinition(
+	id uint,
+	updates map[string]interface{},
+) (*models.MetricDefinition, error) {
+	def, err := r.GetMetricDefinitionByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.db.Model(def).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return r.GetMetricDefinitionByID(id)
+}
+
+func (r *metricRepo) DeleteMetricDefinition(id uint) error {
+	def, err := r.GetMetricDefinitionByID(id)
+	if err != nil {
+		return err
+	}
+
+	// Счетчик, по которому уже есть отчеты, удалить нельзя - только отключить
+	query := r.db.Model(&models.StatMetric{}).
+		Joins("JOIN stat_dailies ON stat_dailies.id = stat_metrics.stat_daily_id").
+		Where("stat_metrics.code = ?", def.Code)
+	if def.RegionID != 0 {
+		query = query.Where("stat_dailies.region_id = ?", def.RegionID)
+	}
+
+	var used int64
+	if err := query.Count(&used).Error; err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+	if used > 0 {
+		return fmt.Errorf("%w: metric is used in reports, deactivate it instead", errs.ErrConflict)
+	}
+
+	if err := r.db.Delete(def).Error; err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return nil
+}
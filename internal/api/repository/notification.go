@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Через сколько незавершенная отправка (например, прерванная перезапуском) может быть повторена
+const notificationPendingTimeout = 10 * time.Minute
+
+type NotificationRepo interface {
+	// Журнал уведомлений региона за дату, пустой вид - все виды
+	GetNotifications(regionID uint, date string, kind string) ([]models.NotificationLog, error)
+	// Занять отправку уведомления: false, если оно уже отправлено или отправляется.
+	// Неудачные отправки можно повторить, force повторяет и отправленные
+	ClaimNotification(entry *models.NotificationLog, force bool) (bool, error)
+	// Записать результат отправки, nil - доставлено
+	FinishNotification(entry *models.NotificationLog, sendErr error) error
+}
+
+type notificationRepo struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepo(db *gorm.DB) NotificationRepo {
+	return &notificationRepo{db: db}
+}
+
+func (r *notificationRepo) GetNotifications(
+	regionID uint,
+	date string,
+	kind string,
+) ([]models.NotificationLog, error) {
+	var entries []models.NotificationLog
+
+	query := r.db.Where("region_id = ? AND date = ?", regionID, date)
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if err := query.Order("kind, recipient, channel").Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return entries, nil
+}
+
+func (r *notificationRepo) ClaimNotification(entry *models.NotificationLog, force bool) (bool, error) {
+	claimed := false
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		entry.Status = models.NotificationPending
+		entry.Attempts = 1
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(entry)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			claimed = true
+			return nil
+		}
+
+		var existing models.NotificationLog
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(
+				"kind = ? AND region_id = ? AND date = ? AND recipient = ? AND channel = ?",
+				entry.Kind, entry.RegionID, entry.Date, entry.Recipient, entry.Channel,
+			).
+			First(&existing).Error; err != nil {
+			return err
+		}
+
+		switch existing.Status {
+		case models.NotificationSent:
+			if !force {
+				*entry = existing
+				return nil
+			}
+		case models.NotificationPending:
+			if time.Since(existing.UpdatedAt) < notificationPendingTimeout {
+				*entry = existing
+				return nil
+			}
+		}
+
+		existing.Address = entry.Address
+		existing.Status = models.NotificationPending
+		existing.Error = ""
+		existing.Attempts++
+		if err := tx.Save(&existing).Error; err != nil {
+			return err
+		}
+
+		*entry = existing
+		claimed = true
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, fmt.Errorf("%w: notification log entry disappeared", errs.ErrConflict)
+		}
+		return false, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return claimed, nil
+}
+
+func (r *notificationRepo) FinishNotification(entry *models.NotificationLog, sendErr error) error {
+	entry.Status = models.NotificationSent
+	entry.Error = ""
+	if sendErr != nil {
+		entry.Status = models.NotificationFailed
+		entry.Error = sendErr.Error()
+	}
+
+	if err := r.db.Model(entry).Updates(map[string]any{
+		"status":     entry.Status,
+		"error":      entry.Error,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return nil
+}
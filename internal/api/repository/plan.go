@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PlanRepo interface {
+	// Цели региона за месяц (month - первый день месяца YYYY-MM-01), включая цели пользователей
+	GetPlanTargets(regionID uint, month string) ([]models.PlanTarget, error)
+	// Цели пользователя за месяц
+	GetUserPlanTargets(regionID uint, name string, month string) ([]models.PlanTarget, error)
+	// Создать цель или заменить существующую на тот же месяц, пользователя и продукт
+	SavePlanTarget(target *models.PlanTarget) error
+	DeletePlanTarget(id uint) error
+}
+
+type planRepo struct {
+	db *gorm.DB
+}
+
+func NewPlanRepo(db *gorm.DB) PlanRepo {
+	return &planRepo{db: db}
+}
+
+func (r *planRepo) GetPlanTargets(regionID uint, month string) ([]models.PlanTarget, error) {
+	var targets []models.PlanTarget
+
+	if err := r.db.Where("region_id = ? AND month = ?", regionID, month).
+		Order("name, code").
+		Find(&targets).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return targets, nil
+}
+
+func (r *planRepo) GetUserPlanTargets(
+	regionID uint,
+	name string,
+	month string,
+) ([]models.PlanTarget, error) {
+	var targets []models.PlanTarget
+
+	if err := r.db.Where("region_id = ? AND name = ? AND month = ?", regionID, name, month).
+		Order("code").
+		Find(&targets).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return targets, nil
+}
+
+func (r *planRepo) SavePlanTarget(target *models.PlanTarget) error {
+	err := r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "month"},
+			{Name: "region_id"},
+			{Name: "name"},
+			{Name: "product_id"},
+		},
+		DoUpdates: clause.AssignmentColumns([]string{"amount", "weights", "updated_by", "updated_at"}),
+	}).Create(target).Error
+	if err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	// При замене существующей цели ID в target не заполняется, перечитываем запись
+	if err := r.db.Where(
+		"month = ? AND region_id = ? AND name = ? AND product_id = ?",
+		target.Month,
+		target.RegionID,
+		target.Name,
+		target.ProductID,
+	).First(target).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.ErrNotFound
+		}
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return nil
+}
+
+func (r *planRepo) DeletePlanTarget(id uint) error {
+	result := r.db.Delete(&models.PlanTarget{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
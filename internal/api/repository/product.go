@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"gorm.io/gorm"
+)
+
+type ProductRepo interface {
+	GetProducts(activeOnly bool) ([]models.Product, error)
+	GetProductByID(id uint) (*models.Product, error)
+	CreateProduct(product *models.Product) error
+	UpdateProduct(id uint, updates map[string]interface{}) (*models.Product, error)
+	DeleteProduct(id uint) error
+}
+
+type productRepo struct {
+	db *gorm.DB
+}
+
+func NewProductRepo(db *gorm.DB) ProductRepo {
+	return &productRepo{db: db}
+}
+
+func (r *productRepo) GetProducts(activeOnly bool) ([]models.Product, error) {
+	var products []models.Product
+
+	query := r.db.Order("position, id")
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+
+	if err := query.Find(&products).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return products, nil
+}
+
+func (r *productRepo) GetProductByID(id uint) (*models.Product, error) {
+	var product models.Product
+	if err := r.db.First(&product, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return &product, nil
+}
+
+func (r *productRepo) CreateProduct(product *models.Product) error {
+	if err := r.db.Create(product).Error; err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "duplicate key") {
+			return fmt.Errorf("%w: product code %s already exists", errs.ErrUniqueName, product.Code)
+		}
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return nil
+}
+
+func (r *productRepo) UpdateProduct(
+	id uint,
+	updates map[string]interface{},
+) (*models.Product, error) {
+	product, err := r.GetProductByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.db.Model(product).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return r.GetProductByID(id)
+}
+
+func (r *productRepo) DeleteProduct(id uint) error {
+	// Продукт, по которому уже есть отчеты, удалить нельзя - только отключить
+	var used int64
+	if err := r.db.Model(&models.StatProduct{}).Where("product_id = ?", id).Count(&used).Error; err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+	if used > 0 {
+		return fmt.Errorf("%w: product is used in reports, deactivate it instead", errs.ErrConflict)
+	}
+
+	if err := r.db.Model(&models.PlanTarget{}).Where("product_id = ?", id).Count(&used).Error; err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+	if used > 0 {
+		return fmt.Errorf("%w: product has plan targets, deactivate it instead", errs.ErrConflict)
+	}
+
+	result := r.db.Delete(&models.Product{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
@@ -10,39 +11,175 @@ import (
 	"github.com/Wladim1r/statcounter/internal/lib/summa"
 	"github.com/Wladim1r/statcounter/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type DjnRepo interface {
-	PostStat(stat *models.StatDaily) error
-	PatchStat(regionID uint, stat *models.StatDaily) error
+	// Изменения отчетов сохраняются в истории ревизий вместе с автором
+	PostStat(stat *models.StatDaily, actor models.Actor) error
+	// Создать несколько отчетов в одной транзакции: сохраняются все или ни один
+	PostStats(stats []*models.StatDaily, actor models.Actor) error
+	// Изменить отчет, если его ETag совпадает с ifMatch. Обновленная запись возвращается в stat
+	PatchStat(regionID uint, stat *models.StatDaily, actor models.Actor, ifMatch string) error
+	// Изменить отчет по его ID (административная правка)
+	PatchStatByID(id uint, stat *models.StatDaily, actor models.Actor, ifMatch string) error
+	GetStatByID(id uint) (*models.StatDaily, error)
+	// Удалить отчет и убрать его из summa, возвращает удаленную запись
+	DeleteStat(id uint, actor models.Actor) (*models.StatDaily, error)
+	// Восстановить отчет из ревизии: обновить существующий или создать заново удаленный
+	RestoreStat(revisionID uint, stat *models.StatDaily, actor models.Actor) error
 	GetStatsByRegion(regionID uint) ([]models.StatDaily, error)
 	GetStatsByRegionAndUser(regionID uint, username string) ([]models.StatDaily, error)
-	DeleteOlderThan(cutoffDate time.Time) error
+	// Удалить отчеты региона старше cutoffDate, предварительно добавив их в месячные сводки
+	DeleteOlderThan(regionID uint, cutoffDate time.Time) error
 	GetStatsByMonth(regionID uint, date string) ([]models.StatDaily, error)
 	GetStatsByMonthAndUser(regionID uint, username string, date string) ([]models.StatDaily, error)
+	// Отчеты региона за период включительно, пустой срез если отчетов нет
+	GetStatsByPeriod(regionID uint, from string, to string) ([]models.StatDaily, error)
+	RebuildStats() error
+	// Агрегат и количество отчетов региона за дату
+	GetRegionTotals(regionID uint, date string) (models.StatDaily, int, error)
+	// Агрегаты и количество отчетов всех регионов за дату
+	GetAllRegionTotals(date string) (map[uint]models.StatDaily, map[uint]int, error)
+	// Сумма по всем регионам за дату
+	GetTotals(date string) (models.StatDaily, int, error)
+	// Применить к summa необработанные события outbox
+	DispatchStatEvents() error
+	// Сверить summa с БД и исправить расхождения, возвращает число исправлений
+	ReconcileStats() (int, error)
 }
 
 type djnRepo struct {
-	db *gorm.DB
+	db         *gorm.DB
+	aggregator summa.Aggregator
 }
 
-func NewDjnRepo(db *gorm.DB) DjnRepo {
-	return &djnRepo{db: db}
+func NewDjnRepo(db *gorm.DB, aggregator summa.Aggregator) DjnRepo {
+	return &djnRepo{db: db, aggregator: aggregator}
 }
 
-func (r *djnRepo) DeleteOlderThan(cutoffDate time.Time) error {
-	if err := r.db.Exec("DELETE FROM stat_dailies WHERE date < $1", cutoffDate).Error; err != nil {
+func (r *djnRepo) DeleteOlderThan(regionID uint, cutoffDate time.Time) error {
+	cutoff := cutoffDate.Format("2006-01-02")
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Строки блокируются до конца транзакции: очистка на другой реплике дождется удаления
+		// и не найдет их, иначе отчеты попали бы в месячные сводки дважды
+		var stats []models.StatDaily
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Products").Preload("Metrics").
+			Where("region_id = ? AND date < ?", regionID, cutoff).
+			Find(&stats).Error; err != nil {
+			return err
+		}
+		if len(stats) == 0 {
+			return nil
+		}
+
+		// Перед удалением сохраняем данные в месячные сводки
+		if err := archiveStats(tx, stats); err != nil {
+			return fmt.Errorf("failed to archive stats: %w", err)
+		}
+
+		// Удаляем только заархивированные строки
+		ids := make([]uint, 0, len(stats))
+		for _, stat := range stats {
+			ids = append(ids, stat.ID)
+		}
+		if err := tx.Exec("DELETE FROM stat_dailies WHERE id IN ?", ids).Error; err != nil {
+			return err
+		}
+
+		return summa.RecordPurge(tx, regionID, cutoffDate)
+	})
+	if err != nil {
 		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
 	}
 
-	summa.ClearAllStats()
+	r.dispatchStats()
+
+	return nil
+}
+
+func (r *djnRepo) RebuildStats() error {
+	if err := r.aggregator.Rebuild(); err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
 
 	return nil
 }
 
+func (r *djnRepo) GetRegionTotals(regionID uint, date string) (models.StatDaily, int, error) {
+	stat, quantity, err := r.aggregator.RegionStats(regionID, date)
+	if err != nil {
+		return models.StatDaily{}, 0, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return stat, quantity, nil
+}
+
+func (r *djnRepo) GetAllRegionTotals(date string) (map[uint]models.StatDaily, map[uint]int, error) {
+	stats, quantities, err := r.aggregator.AllRegionStats(date)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return stats, quantities, nil
+}
+
+func (r *djnRepo) GetTotals(date string) (models.StatDaily, int, error) {
+	stat, quantity, err := r.aggregator.TotalStats(date)
+	if err != nil {
+		return models.StatDaily{}, 0, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return stat, quantity, nil
+}
+
+func (r *djnRepo) DispatchStatEvents() error {
+	if err := r.aggregator.Dispatch(); err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return nil
+}
+
+func (r *djnRepo) ReconcileStats() (int, error) {
+	repaired, err := r.aggregator.Reconcile()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return repaired, nil
+}
+
+// Применить закоммиченные изменения к summa сразу после записи.
+// Если это не удалось, события останутся в outbox и их применит фоновый проход
+func (r *djnRepo) dispatchStats() {
+	if err := r.aggregator.Dispatch(); err != nil {
+		log.Printf("failed to dispatch stat events: %v", err)
+	}
+}
+
+func (r *djnRepo) GetStatsByPeriod(
+	regionID uint,
+	from string,
+	to string,
+) ([]models.StatDaily, error) {
+	var stats []models.StatDaily
+
+	if err := r.db.Preload("Products").Preload("Metrics").
+		Where("region_id = ? AND date BETWEEN ? AND ?", regionID, from, to).
+		Order("date, name").
+		Find(&stats).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return stats, nil
+}
+
 func (r *djnRepo) GetStatsByMonth(regionID uint, date string) ([]models.StatDaily, error) {
 	var stats []models.StatDaily
-	result := r.db.Where("region_id = ? AND date = ?", regionID, date).Find(&stats)
+	result := r.db.Preload("Products").Preload("Metrics").Where("region_id = ? AND date = ?", regionID, date).Find(&stats)
 	if result.Error != nil {
 		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, result.Error)
 	}
@@ -59,7 +196,8 @@ func (r *djnRepo) GetStatsByMonthAndUser(
 	date string,
 ) ([]models.StatDaily, error) {
 	var stats []models.StatDaily
-	result := r.db.Where("region_id = ? AND name = ? AND date = ?", regionID, username, date).
+	result := r.db.Preload("Products").Preload("Metrics").
+		Where("region_id = ? AND name = ? AND date = ?", regionID, username, date).
 		Find(&stats)
 	if result.Error != nil {
 		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, result.Error)
@@ -71,45 +209,261 @@ func (r *djnRepo) GetStatsByMonthAndUser(
 	return stats, nil
 }
 
-func (r *djnRepo) PatchStat(regionID uint, stat *models.StatDaily) error {
-	// Сначала получаем старые данные
-	var oldStat models.StatDaily
-	if err := r.db.Where("region_id = ? AND name = ?", regionID, stat.Name).First(&oldStat).Error; err != nil {
+func (r *djnRepo) GetStatByID(id uint) (*models.StatDaily, error) {
+	var stat models.StatDaily
+	if err := r.db.Preload("Products").Preload("Metrics").First(&stat, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errs.ErrNotFound
+			return nil, errs.ErrNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return &stat, nil
+}
+
+func (r *djnRepo) DeleteStat(id uint, actor models.Actor) (*models.StatDaily, error) {
+	var stat models.StatDaily
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("Products").Preload("Metrics").First(&stat, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errs.ErrNotFound
+			}
+			return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+		}
+
+		// Значения продуктов и счетчиков удаляются каскадно
+		if err := tx.Delete(&models.StatDaily{}, stat.ID).Error; err != nil {
+			return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
 		}
-		return fmt.Errorf("%w: failed to get old record %v", errs.ErrDBOperation, err)
+
+		if err := recordRevision(tx, models.RevisionDelete, actor, &stat, nil, nil); err != nil {
+			return fmt.Errorf("%w: failed to record revision %v", errs.ErrDBOperation, err)
+		}
+		if err := summa.RecordEvent(tx, &stat, nil); err != nil {
+			return fmt.Errorf("%w: failed to record stat event %v", errs.ErrDBOperation, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Обновляем запись
-	result := r.db.Where("region_id = ? AND name = ?", regionID, stat.Name).Updates(stat)
+	r.dispatchStats()
+
+	return &stat, nil
+}
+
+func (r *djnRepo) PatchStat(
+	regionID uint,
+	stat *models.StatDaily,
+	actor models.Actor,
+	ifMatch string,
+) error {
+	return r.patchStat(stat, actor, ifMatch, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("region_id = ? AND name = ? AND date = ?", regionID, stat.Name, stat.Date)
+	})
+}
+
+func (r *djnRepo) PatchStatByID(
+	id uint,
+	stat *models.StatDaily,
+	actor models.Actor,
+	ifMatch string,
+) error {
+	return r.patchStat(stat, actor, ifMatch, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("id = ?", id)
+	})
+}
+
+// Обновить значения отчета, найденного запросом find, и пересчитать summa.
+// Строка блокируется до конца транзакции, поэтому событие для summa содержит
+// ровно то состояние, которое заменяет обновление
+func (r *djnRepo) patchStat(
+	stat *models.StatDaily,
+	actor models.Actor,
+	ifMatch string,
+	find func(tx *gorm.DB) *gorm.DB,
+) error {
+	var oldStat, newStat models.StatDaily
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Сначала получаем старые данные
+		if err := find(tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Products").Preload("Metrics")).
+			First(&oldStat).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errs.ErrNotFound
+			}
+			return fmt.Errorf("%w: failed to get old record %v", errs.ErrDBOperation, err)
+		}
+
+		if !oldStat.MatchesETag(ifMatch) {
+			return fmt.Errorf(
+				"%w: report has been modified, current version is %s",
+				errs.ErrPreconditionFailed,
+				oldStat.ETag(),
+			)
+		}
+
+		if err := updateStat(tx, &oldStat, stat, &newStat); err != nil {
+			return err
+		}
+
+		if err := recordRevision(tx, models.RevisionUpdate, actor, &oldStat, &newStat, nil); err != nil {
+			return fmt.Errorf("%w: failed to record revision %v", errs.ErrDBOperation, err)
+		}
+		if err := summa.RecordEvent(tx, &oldStat, &newStat); err != nil {
+			return fmt.Errorf("%w: failed to record stat event %v", errs.ErrDBOperation, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.dispatchStats()
+	*stat = newStat
+
+	return nil
+}
+
+func (r *djnRepo) RestoreStat(revisionID uint, stat *models.StatDaily, actor models.Actor) error {
+	var oldStat, newStat models.StatDaily
+	existed := true
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Products").Preload("Metrics").
+			Where("region_id = ? AND name = ? AND date = ?", stat.RegionID, stat.Name, stat.Date).
+			First(&oldStat).Error
+
+		switch {
+		case err == nil:
+			if err := updateStat(tx, &oldStat, stat, &newStat); err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			// Отчет был удален - создаем его заново
+			existed = false
+			stat.Version = 1
+			if err := tx.Create(stat).Error; err != nil {
+				return fmt.Errorf("%w: failed to recreate record %v", errs.ErrDBOperation, err)
+			}
+			newStat = *stat
+		default:
+			return fmt.Errorf("%w: failed to get old record %v", errs.ErrDBOperation, err)
+		}
+
+		var old *models.StatDaily
+		if existed {
+			old = &oldStat
+		}
+		if err := recordRevision(tx, models.RevisionRestore, actor, old, &newStat, &revisionID); err != nil {
+			return fmt.Errorf("%w: failed to record revision %v", errs.ErrDBOperation, err)
+		}
+		if err := summa.RecordEvent(tx, old, &newStat); err != nil {
+			return fmt.Errorf("%w: failed to record stat event %v", errs.ErrDBOperation, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.dispatchStats()
+
+	return nil
+}
+
+// Записать в oldStat значения продуктов и счетчиков из stat и перечитать результат в newStat
+func updateStat(tx *gorm.DB, oldStat, stat, newStat *models.StatDaily) error {
+	// Обновляем запись только если ее версия не изменилась с момента чтения,
+	// идентификацию отчета и автора создания не меняем
+	result := tx.Model(&models.StatDaily{}).
+		Where("id = ? AND version = ?", oldStat.ID, oldStat.Version).
+		Updates(map[string]interface{}{
+			"version":    gorm.Expr("version + 1"),
+			"updated_by": stat.UpdatedBy,
+			"updated_at": time.Now(),
+		})
 	if result.Error != nil {
 		return fmt.Errorf("%w: failed update %v", errs.ErrDBOperation, result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return errs.ErrNotFound
+		return fmt.Errorf("%w: report has been modified concurrently", errs.ErrConflict)
+	}
+
+	// Обновляем значения по продуктам
+	for _, p := range stat.Products {
+		p.ID = 0
+		p.StatDailyID = oldStat.ID
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "stat_daily_id"}, {Name: "product_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"plan", "fact", "dif", "plan_source", "claimed_plan"}),
+		}).Create(&p).Error; err != nil {
+			return fmt.Errorf("%w: failed update product %s %v", errs.ErrDBOperation, p.Code, err)
+		}
 	}
 
-	// Обновляем summa
-	summa.UpdateStatForRegion(regionID, oldStat, *stat)
+	// Обновляем значения счетчиков
+	for _, m := range stat.Metrics {
+		m.ID = 0
+		m.StatDailyID = oldStat.ID
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "stat_daily_id"}, {Name: "code"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value"}),
+		}).Create(&m).Error; err != nil {
+			return fmt.Errorf("%w: failed update metric %s %v", errs.ErrDBOperation, m.Code, err)
+		}
+	}
+
+	// Перечитываем запись, чтобы summa получила фактическое состояние из БД
+	if err := tx.Preload("Products").Preload("Metrics").First(newStat, oldStat.ID).Error; err != nil {
+		return fmt.Errorf("%w: failed to reload record %v", errs.ErrDBOperation, err)
+	}
 
 	return nil
 }
 
-func (r *djnRepo) PostStat(stat *models.StatDaily) error {
-	result := r.db.Create(stat)
-	if result.Error != nil {
+func (r *djnRepo) PostStat(stat *models.StatDaily, actor models.Actor) error {
+	return r.PostStats([]*models.StatDaily{stat}, actor)
+}
+
+func (r *djnRepo) PostStats(stats []*models.StatDaily, actor models.Actor) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, stat := range stats {
+			stat.Version = 1
+
+			if err := tx.Create(stat).Error; err != nil {
+				return err
+			}
+
+			if err := recordRevision(tx, models.RevisionCreate, actor, nil, stat, nil); err != nil {
+				return err
+			}
+
+			if err := summa.RecordEvent(tx, nil, stat); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
 		// Проверяем различные типы ошибок уникальности
-		errorStr := strings.ToLower(result.Error.Error())
+		errorStr := strings.ToLower(err.Error())
 		if strings.Contains(errorStr, "duplicate key") ||
 			strings.Contains(errorStr, "unique constraint") ||
 			strings.Contains(errorStr, "idx_unique_daily_stat") {
 			return fmt.Errorf("%w: %s", errs.ErrUniqueName, "You have already submitted data for today")
 		}
-		return fmt.Errorf("%w: %v", errs.ErrDBOperation, result.Error)
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
 	}
 
-	summa.AddStatForRegion(stat.RegionID, *stat)
+	r.dispatchStats()
 	return nil
 }
 
@@ -117,7 +471,7 @@ func (r *djnRepo) GetStatsByRegion(regionID uint) ([]models.StatDaily, error) {
 	var stats []models.StatDaily
 	today := time.Now().Format("2006-01-02") // YYYY-MM-DD
 
-	result := r.db.Where("region_id = ? AND date = ?", regionID, today).Find(&stats)
+	result := r.db.Preload("Products").Preload("Metrics").Where("region_id = ? AND date = ?", regionID, today).Find(&stats)
 	if result.Error != nil {
 		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, result.Error)
 	}
@@ -136,7 +490,8 @@ func (r *djnRepo) GetStatsByRegionAndUser(
 	var stats []models.StatDaily
 	today := time.Now().Format("2006-01-02") // YYYY-MM-DD
 
-	result := r.db.Where("region_id = ? AND name = ? AND date = ?", regionID, username, today).
+	result := r.db.Preload("Products").Preload("Metrics").
+		Where("region_id = ? AND name = ? AND date = ?", regionID, username, today).
 		Find(&stats)
 	if result.Error != nil {
 		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, result.Error)
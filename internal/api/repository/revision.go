@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"gorm.io/gorm"
+)
+
+// Отбор ревизий, пустые поля не ограничивают выборку
+type RevisionFilter struct {
+	ReportID uint
+	RegionID uint
+	Name     string
+	Date     string
+}
+
+type RevisionRepo interface {
+	// Ревизии в порядке их создания
+	GetRevisions(filter RevisionFilter) ([]models.StatRevision, error)
+	GetRevisionByID(id uint) (*models.StatRevision, error)
+}
+
+type revisionRepo struct {
+	db *gorm.DB
+}
+
+func NewRevisionRepo(db *gorm.DB) RevisionRepo {
+	return &revisionRepo{db: db}
+}
+
+func (r *revisionRepo) GetRevisions(filter RevisionFilter) ([]models.StatRevision, error) {
+	query := r.db.Model(&models.StatRevision{})
+	if filter.ReportID != 0 {
+		query = query.Where("stat_daily_id = ?", filter.ReportID)
+	}
+	if filter.RegionID != 0 {
+		query = query.Where("region_id = ?", filter.RegionID)
+	}
+	if filter.Name != "" {
+		query = query.Where("name = ?", filter.Name)
+	}
+	if filter.Date != "" {
+		query = query.Where("date = ?", filter.Date)
+	}
+
+	var revisions []models.StatRevision
+	if err := query.Order("id").Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	for i := range revisions {
+		revisions[i].Date = normalizeDate(revisions[i].Date)
+	}
+
+	return revisions, nil
+}
+
+func (r *revisionRepo) GetRevisionByID(id uint) (*models.StatRevision, error) {
+	var revision models.StatRevision
+	if err := r.db.First(&revision, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+	revision.Date = normalizeDate(revision.Date)
+
+	return &revision, nil
+}
+
+// Сохранить ревизию отчета в транзакции изменения. oldStat пуст при создании, newStat - при удалении
+func recordRevision(
+	tx *gorm.DB,
+	action string,
+	actor models.Actor,
+	oldStat, newStat *models.StatDaily,
+	restoredFrom *uint,
+) error {
+	ref := newStat
+	if ref == nil {
+		ref = oldStat
+	}
+
+	revision := models.StatRevision{
+		StatDailyID:  ref.ID,
+		RegionID:     ref.RegionID,
+		Name:         ref.Name,
+		Date:         normalizeDate(ref.Date),
+		Action:       action,
+		Actor:        actor.Username,
+		IP:           actor.IP,
+		RestoredFrom: restoredFrom,
+	}
+
+	var err error
+	if oldStat != nil {
+		if revision.OldData, err = json.Marshal(oldStat); err != nil {
+			return fmt.Errorf("failed to encode old state: %w", err)
+		}
+	}
+	if newStat != nil {
+		if revision.NewData, err = json.Marshal(newStat); err != nil {
+			return fmt.Errorf("failed to encode new state: %w", err)
+		}
+	}
+
+	return tx.Create(&revision).Error
+}
+
+// Дата из БД может прийти с временем (2006-01-02T00:00:00Z)
+func normalizeDate(date string) string {
+	if len(date) > len("2006-01-02") {
+		return date[:len("2006-01-02")]
+	}
+	return date
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ScoringRepo interface {
+	GetFormulas() ([]models.ScoringFormula, error)
+	// Формулы региона и общая (RegionID 0), если заданы
+	GetRegionFormulas(regionID uint) ([]models.ScoringFormula, error)
+	SaveFormula(formula *models.ScoringFormula) error
+	DeleteFormula(id uint) error
+}
+
+type scoringRepo struct {
+	db *gorm.DB
+}
+
+func NewScoringRepo(db *gorm.DB) ScoringRepo {
+	return &scoringRepo{db: db}
+}
+
+func (r *scoringRepo) GetFormulas() ([]models.ScoringFormula, error) {
+	var formulas []models.ScoringFormula
+
+	if err := r.db.Order("region_id").Find(&formulas).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return formulas, nil
+}
+
+func (r *scoringRepo) GetRegionFormulas(regionID uint) ([]models.ScoringFormula, error) {
+	var formulas []models.ScoringFormula
+
+	if err := r.db.Where("region_id IN ?", []uint{0, regionID}).
+		Order("region_id").
+		Find(&formulas).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return formulas, nil
+}
+
+func (r *scoringRepo) SaveFormula(formula *models.ScoringFormula) error {
+	if err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "region_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "terms", "updated_by", "updated_at"}),
+	}).Create(formula).Error; err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	if err := r.db.Where("region_id = ?", formula.RegionID).First(formula).Error; err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	return nil
+}
+
+func (r *scoringRepo) DeleteFormula(id uint) error {
+	result := r.db.Delete(&models.ScoringFormula{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
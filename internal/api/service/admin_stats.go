@@ -0,0 +1,215 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/repository"
+	"github.com/Wladim1r/statcounter/internal/auth"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+)
+
+// Источник списка регионов (реализуется auth.AuthService)
+type RegionLister interface {
+	GetRegions() ([]auth.Region, error)
+}
+
+// Итоги одного региона за дату
+type RegionStats struct {
+	RegionID   uint             `json:"region_id"`
+	RegionName string           `json:"region_name"`
+	Total      models.StatTotal `json:"total"`
+}
+
+// Итоги по всем регионам и по компании за дату
+type CompanyStats struct {
+	Date    string           `json:"date"`
+	Product string           `json:"product,omitempty"`
+	Total   models.StatTotal `json:"total"`
+	Regions []RegionStats    `json:"regions"`
+}
+
+// Отчеты пользователей региона за дату
+type RegionReports struct {
+	Date       string             `json:"date"`
+	Product    string             `json:"product,omitempty"`
+	RegionID   uint               `json:"region_id"`
+	RegionName string             `json:"region_name"`
+	Total      models.StatTotal   `json:"total"`
+	Reports    []models.StatDaily `json:"reports"`
+}
+
+type AdminStatsService interface {
+	GetCompanyStats(date string, product string) (*CompanyStats, error)
+	GetRegionReports(regionID uint, date string, product string) (*RegionReports, error)
+}
+
+type adminStatsService struct {
+	repo     repository.DjnRepo
+	products repository.ProductRepo
+	regions  RegionLister
+	scores   ScoringService
+}
+
+func NewAdminStatsService(
+	repo repository.DjnRepo,
+	products repository.ProductRepo,
+	regions RegionLister,
+	scores ScoringService,
+) AdminStatsService {
+	return &adminStatsService{repo: repo, products: products, regions: regions, scores: scores}
+}
+
+func (s *adminStatsService) GetCompanyStats(date string, product string) (*CompanyStats, error) {
+	date, err := s.validateFilters(date, product)
+	if err != nil {
+		return nil, err
+	}
+
+	regions, err := s.regions.GetRegions()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	allStats, allQuantities, err := s.repo.GetAllRegionTotals(date)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CompanyStats{
+		Date:    date,
+		Product: product,
+		Regions: make([]RegionStats, 0, len(regions)),
+	}
+
+	for _, region := range regions {
+		score, err := s.scores.Scorer(region.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		// Оценка считается по всем продуктам, фильтр влияет только на выдачу
+		regionStat := allStats[region.ID]
+		total := models.NewStatTotal("", region.ID, allQuantities[region.ID], filterProduct(regionStat, product))
+		total.Score = score(regionStat)
+
+		result.Regions = append(result.Regions, RegionStats{
+			RegionID:   region.ID,
+			RegionName: region.Name,
+			Total:      total,
+		})
+	}
+
+	totalStat, totalQuantity, err := s.repo.GetTotals(date)
+	if err != nil {
+		return nil, err
+	}
+	score, err := s.scores.Scorer(0)
+	if err != nil {
+		return nil, err
+	}
+	result.Total = models.NewStatTotal("", 0, totalQuantity, filterProduct(totalStat, product))
+	result.Total.Score = score(totalStat)
+
+	return result, nil
+}
+
+func (s *adminStatsService) GetRegionReports(
+	regionID uint,
+	date string,
+	product string,
+) (*RegionReports, error) {
+	date, err := s.validateFilters(date, product)
+	if err != nil {
+		return nil, err
+	}
+
+	regions, err := s.regions.GetRegions()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	var regionName string
+	for _, region := range regions {
+		if region.ID == regionID {
+			regionName = region.Name
+		}
+	}
+	if regionName == "" {
+		return nil, fmt.Errorf("%w: region %d", errs.ErrNotFound, regionID)
+	}
+
+	stats, err := s.repo.GetStatsByPeriod(regionID, date, date)
+	if err != nil {
+		return nil, err
+	}
+
+	score, err := s.scores.Scorer(regionID)
+	if err != nil {
+		return nil, err
+	}
+
+	scoreStats(score, stats)
+	for i := range stats {
+		stats[i] = filterProduct(stats[i], product)
+	}
+
+	regionStat, regionQuantity, err := s.repo.GetRegionTotals(regionID, date)
+	if err != nil {
+		return nil, err
+	}
+
+	total := models.NewStatTotal("", regionID, regionQuantity, filterProduct(regionStat, product))
+	total.Score = score(regionStat)
+
+	return &RegionReports{
+		Date:       date,
+		Product:    product,
+		RegionID:   regionID,
+		RegionName: regionName,
+		Total:      total,
+		Reports:    stats,
+	}, nil
+}
+
+// Проверить дату (по умолчанию сегодня) и код продукта
+func (s *adminStatsService) validateFilters(date string, product string) (string, error) {
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return "", fmt.Errorf("%w: %v", errs.ErrBadRequest, "Invalid date format. Use YYYY-MM-DD")
+	}
+
+	if product == "" {
+		return date, nil
+	}
+
+	catalog, err := s.products.GetProducts(false)
+	if err != nil {
+		return "", err
+	}
+	for _, p := range catalog {
+		if p.Code == product {
+			return date, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: unknown product %s", errs.ErrBadRequest, product)
+}
+
+// Оставить в отчете только указанный продукт (пустой код - все продукты)
+func filterProduct(stat models.StatDaily, product string) models.StatDaily {
+	if product == "" {
+		return stat
+	}
+
+	var products []models.StatProduct
+	if p := stat.Product(product); p != nil {
+		products = append(products, *p)
+	}
+	stat.Products = products
+
+	return stat
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/repository"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/lib/summa"
+	"github.com/Wladim1r/statcounter/internal/models"
+)
+
+// Месячные итоги региона и его пользователей
+type MonthlyArchive struct {
+	Month    string                  `json:"month"`
+	RegionID uint                    `json:"region_id"`
+	Region   models.MonthlySummary   `json:"region"`
+	Users    []models.MonthlySummary `json:"users"`
+}
+
+type ArchiveService interface {
+	// Итоги за месяц YYYY-MM; с username в Users попадает только этот пользователь
+	GetMonthlyArchive(regionID uint, month string, username string) (*MonthlyArchive, error)
+}
+
+type archiveService struct {
+	repo    repository.ArchiveRepo
+	djnRepo repository.DjnRepo
+}
+
+func NewArchiveService(repo repository.ArchiveRepo, djnRepo repository.DjnRepo) ArchiveService {
+	return &archiveService{repo: repo, djnRepo: djnRepo}
+}
+
+// Итоги пользователей региона за месяц вместе с днями, удаленными по сроку хранения.
+// С username в итоги попадает только этот пользователь
+func monthlyUserTotals(
+	archive ArchiveService,
+	regionID uint,
+	month time.Time,
+	username string,
+) ([]models.StatTotal, error) {
+	result, err := archive.GetMonthlyArchive(regionID, month.Format("2006-01"), username)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make([]models.StatTotal, 0, len(result.Users))
+	for _, user := range result.Users {
+		totals = append(totals, models.NewStatTotal(user.Name, regionID, user.Reports, user.Stat()))
+	}
+	return totals, nil
+}
+
+func (s *archiveService) GetMonthlyArchive(
+	regionID uint,
+	month string,
+	username string,
+) (*MonthlyArchive, error) {
+	if month == "" {
+		return nil, fmt.Errorf(
+			"%w: %v",
+			errs.ErrBadRequest,
+			"Month parameter is required (format: YYYY-MM)",
+		)
+	}
+
+	monthStart, err := time.ParseInLocation("2006-01", month, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrBadRequest, "Invalid month format. Use YYYY-MM")
+	}
+	if monthStart.After(time.Now()) {
+		return nil, fmt.Errorf("%w: %v", errs.ErrBadRequest, "Requested month is in the future")
+	}
+	monthEnd := monthStart.AddDate(0, 1, -1)
+
+	summaries, err := s.repo.GetMonthlySummaries(regionID, monthStart.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Уже удаленные дни хранятся в сводках, оставшиеся - в ежедневных отчетах
+	stats, err := s.djnRepo.GetStatsByPeriod(
+		regionID,
+		monthStart.Format("2006-01-02"),
+		monthEnd.Format("2006-01-02"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(summaries) == 0 && len(stats) == 0 {
+		return nil, errs.ErrNotFound
+	}
+
+	totals := make(map[string]models.StatDaily)
+	reports := make(map[string]int)
+
+	for _, summary := range summaries {
+		totals[summary.Name] = summa.Sum(totals[summary.Name], summary.Stat())
+		reports[summary.Name] += summary.Reports
+	}
+	for _, stat := range stats {
+		for _, name := range []string{stat.Name, ""} {
+			totals[name] = summa.Sum(totals[name], stat)
+			reports[name]++
+		}
+	}
+
+	newSummary := func(name string) models.MonthlySummary {
+		summary := models.MonthlySummary{
+			Month:    month,
+			RegionID: regionID,
+			Name:     name,
+			Reports:  reports[name],
+		}
+		summary.SetStat(totals[name])
+		return summary
+	}
+
+	archive := &MonthlyArchive{
+		Month:    month,
+		RegionID: regionID,
+		Region:   newSummary(""),
+		Users:    []models.MonthlySummary{},
+	}
+
+	names := make([]string, 0, len(totals))
+	for name := range totals {
+		if name != "" && (username == "" || name == username) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		archive.Users = append(archive.Users, newSummary(name))
+	}
+
+	return archive, nil
+}
@@ -0,0 +1,451 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/repository"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/lib/export"
+	"github.com/Wladim1r/statcounter/internal/models"
+)
+
+type BonusRuleRequest struct {
+	RegionID uint               `json:"region_id"` // 0 - для всех регионов
+	Name     string             `json:"name"      binding:"required"`
+	Kind     string             `json:"kind"      binding:"required,oneof=plan metric"`
+	Code     string             `json:"code"      binding:"required"`
+	Base     *float64           `json:"base"`
+	Tiers    []models.BonusTier `json:"tiers"`
+	PerUnit  float64            `json:"per_unit"`
+	Active   *bool              `json:"active"`
+}
+
+type BonusRunRequest struct {
+	Month    string `json:"month"     binding:"required"` // YYYY-MM
+	RegionID uint   `json:"region_id" binding:"required"`
+}
+
+type BonusService interface {
+	GetRules() ([]models.BonusRule, error)
+	CreateRule(req BonusRuleRequest, actor string) (*models.BonusRule, error)
+	UpdateRule(id uint, req BonusRuleRequest, actor string) (*models.BonusRule, error)
+	DeleteRule(id uint) error
+
+	// Расчеты за месяц YYYY-MM, пустой месяц - все расчеты
+	GetRuns(month string) ([]models.BonusRun, error)
+	GetRun(id uint) (*models.BonusRun, error)
+	// Рассчитать премии региона за месяц по отчетам из БД и месячному архиву удаленных дней.
+	// Черновик пересчитывается, утвержденный расчет изменить нельзя
+	Calculate(req BonusRunRequest, actor string) (*models.BonusRun, error)
+	// Утвердить расчет. Утвердить можно только закончившийся месяц
+	LockRun(id uint, actor string) (*models.BonusRun, error)
+	DeleteRun(id uint) error
+	// Начисления расчета в CSV: имя файла и содержимое
+	ExportRun(id uint) (string, []byte, error)
+}
+
+type bonusService struct {
+	repo     repository.BonusRepo
+	products repository.ProductRepo
+	metrics  repository.MetricRepo
+	regions  RegionLister
+	archive  ArchiveService
+}
+
+func NewBonusService(
+	repo repository.BonusRepo,
+	products repository.ProductRepo,
+	metrics repository.MetricRepo,
+	regions RegionLister,
+	archive ArchiveService,
+) BonusService {
+	return &bonusService{
+		repo:     repo,
+		products: products,
+		metrics:  metrics,
+		regions:  regions,
+		archive:  archive,
+	}
+}
+
+func (s *bonusService) GetRules() ([]models.BonusRule, error) {
+	return s.repo.GetBonusRules()
+}
+
+func (s *bonusService) CreateRule(req BonusRuleRequest, actor string) (*models.BonusRule, error) {
+	rule, err := s.buildRule(req, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateBonusRule(rule); err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+func (s *bonusService) UpdateRule(
+	id uint,
+	req BonusRuleRequest,
+	actor string,
+) (*models.BonusRule, error) {
+	rule, err := s.buildRule(req, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.ID = id
+	if err := s.repo.UpdateBonusRule(rule); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetBonusRuleByID(id)
+}
+
+func (s *bonusService) DeleteRule(id uint) error {
+	return s.repo.DeleteBonusRule(id)
+}
+
+func (s *bonusService) GetRuns(month string) ([]models.BonusRun, error) {
+	if month == "" {
+		return s.repo.GetBonusRuns("")
+	}
+
+	start, err := parseMonth(month)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetBonusRuns(start.Format("2006-01-02"))
+}
+
+func (s *bonusService) GetRun(id uint) (*models.BonusRun, error) {
+	return s.repo.GetBonusRunByID(id)
+}
+
+func (s *bonusService) Calculate(req BonusRunRequest, actor string) (*models.BonusRun, error) {
+	start, err := parseMonth(req.Month)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if start.After(now) {
+		return nil, fmt.Errorf("%w: month %s has not started yet", errs.ErrBadRequest, req.Month)
+	}
+	if err := s.checkRegion(req.RegionID); err != nil {
+		return nil, err
+	}
+
+	regionRules, err := s.repo.GetRegionBonusRules(req.RegionID)
+	if err != nil {
+		return nil, err
+	}
+	rules := effectiveBonusRules(regionRules)
+
+	// Дни, удаленные по сроку хранения, берутся из месячных сводок пользователей
+	archive, err := s.archive.GetMonthlyArchive(req.RegionID, start.Format("2006-01"), "")
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		return nil, err
+	}
+
+	run := &models.BonusRun{
+		Month:        start.Format("2006-01-02"),
+		RegionID:     req.RegionID,
+		Status:       models.BonusRunDraft,
+		Rules:        rules,
+		Payouts:      []models.BonusPayout{},
+		CalculatedBy: actor,
+		CalculatedAt: now,
+	}
+
+	var users []models.MonthlySummary
+	if archive != nil {
+		users = archive.Users
+	}
+	for _, user := range users {
+		total := models.NewStatTotal(user.Name, req.RegionID, user.Reports, user.Stat())
+		payout := calculatePayout(rules, total)
+		run.Payouts = append(run.Payouts, payout)
+		run.Total += payout.Total
+	}
+	run.Total = round2(run.Total)
+
+	if err := s.repo.SaveBonusRun(run); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetBonusRunByID(run.ID)
+}
+
+func (s *bonusService) LockRun(id uint, actor string) (*models.BonusRun, error) {
+	run, err := s.repo.GetBonusRunByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Отчеты текущего месяца еще поступают, утвержденный расчет по ним устарел бы
+	start, err := parseMonth(run.Month[:len("2006-01")])
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if now.Before(start.AddDate(0, 1, 0)) {
+		return nil, fmt.Errorf(
+			"%w: month %s has not ended yet",
+			errs.ErrBadRequest,
+			start.Format("2006-01"),
+		)
+	}
+
+	if err := s.repo.LockBonusRun(&models.BonusRun{ID: id, LockedBy: actor, LockedAt: &now}); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetBonusRunByID(id)
+}
+
+func (s *bonusService) DeleteRun(id uint) error {
+	return s.repo.DeleteBonusRun(id)
+}
+
+func (s *bonusService) ExportRun(id uint) (string, []byte, error) {
+	run, err := s.repo.GetBonusRunByID(id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	table := export.Table{Header: []string{"Пользователь", "Отчетов"}}
+	for _, rule := range run.Rules {
+		table.Header = append(table.Header, rule.Name)
+	}
+	table.Header = append(table.Header, "Итого")
+
+	for _, payout := range run.Payouts {
+		// Начисления идут в порядке правил расчета
+		row := []any{payout.Name, payout.Reports}
+		for i := range run.Rules {
+			var amount float64
+			if i < len(payout.Lines) {
+				amount = payout.Lines[i].Amount
+			}
+			row = append(row, amount)
+		}
+		table.Rows = append(table.Rows, append(row, payout.Total))
+	}
+
+	footer := make([]any, len(table.Header))
+	footer[0] = "Итого"
+	footer[len(footer)-1] = run.Total
+	table.Footer = append(table.Footer, footer)
+
+	var buf bytes.Buffer
+	if err := export.WriteCSV(&buf, table); err != nil {
+		return "", nil, err
+	}
+
+	filename := fmt.Sprintf("bonus_%d_%s_%s.csv", run.RegionID, run.Month[:len("2006-01")], run.Status)
+	return filename, buf.Bytes(), nil
+}
+
+// Проверить правило и собрать модель
+func (s *bonusService) buildRule(req BonusRuleRequest, actor string) (*models.BonusRule, error) {
+	if err := s.checkRegion(req.RegionID); err != nil {
+		return nil, err
+	}
+
+	rule := &models.BonusRule{
+		RegionID:  req.RegionID,
+		Name:      req.Name,
+		Kind:      req.Kind,
+		Code:      req.Code,
+		Active:    req.Active == nil || *req.Active,
+		UpdatedBy: actor,
+		UpdatedAt: time.Now(),
+	}
+
+	switch req.Kind {
+	case models.BonusRulePlan:
+		if err := s.checkProduct(req.Code); err != nil {
+			return nil, err
+		}
+		if req.Base != nil && *req.Base < 0 {
+			return nil, fmt.Errorf("%w: base must not be negative", errs.ErrBadRequest)
+		}
+		tiers, err := validateTiers(req.Tiers)
+		if err != nil {
+			return nil, err
+		}
+		rule.Base = req.Base
+		rule.Tiers = tiers
+	case models.BonusRuleMetric:
+		if err := s.checkMetric(req.RegionID, req.Code); err != nil {
+			return nil, err
+		}
+		if req.PerUnit < 0 {
+			return nil, fmt.Errorf("%w: per_unit must not be negative", errs.ErrBadRequest)
+		}
+		rule.PerUnit = req.PerUnit
+	default:
+		return nil, fmt.Errorf("%w: kind must be plan or metric", errs.ErrBadRequest)
+	}
+
+	return rule, nil
+}
+
+func (s *bonusService) checkRegion(regionID uint) error {
+	if regionID == 0 {
+		return nil
+	}
+
+	regions, err := s.regions.GetRegions()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+	for _, region := range regions {
+		if region.ID == regionID {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: region %d not found", errs.ErrBadRequest, regionID)
+}
+
+func (s *bonusService) checkProduct(code string) error {
+	catalog, err := s.products.GetProducts(false)
+	if err != nil {
+		return err
+	}
+	for _, p := range catalog {
+		if p.Code == code {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: unknown product %q", errs.ErrBadRequest, code)
+}
+
+// Общее правило может ссылаться на счетчик любого региона
+func (s *bonusService) checkMetric(regionID uint, code string) error {
+	var defs []models.MetricDefinition
+	var err error
+	if regionID == 0 {
+		defs, err = s.metrics.GetAllMetricDefinitions()
+	} else {
+		defs, err = s.metrics.GetMetricDefinitions(regionID, false)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, def := range defs {
+		if def.Code == code {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: unknown metric %q", errs.ErrBadRequest, code)
+}
+
+// Ступени по возрастанию порога, пороги и ставки неотрицательные и не повторяются
+func validateTiers(tiers []models.BonusTier) ([]models.BonusTier, error) {
+	if len(tiers) == 0 {
+		return nil, fmt.Errorf("%w: plan rule must have at least one tier", errs.ErrBadRequest)
+	}
+
+	sorted := append([]models.BonusTier(nil), tiers...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].From < sorted[j].From })
+
+	for i, tier := range sorted {
+		if tier.From < 0 || tier.Rate < 0 || tier.Amount < 0 {
+			return nil, fmt.Errorf("%w: tier values must not be negative", errs.ErrBadRequest)
+		}
+		if i > 0 && tier.From == sorted[i-1].From {
+			return nil, fmt.Errorf("%w: duplicate tier from %.2f", errs.ErrBadRequest, tier.From)
+		}
+	}
+
+	return sorted, nil
+}
+
+// Правила региона заменяют общие правила того же вида и кода
+func effectiveBonusRules(rules []models.BonusRule) []models.BonusRule {
+	overridden := make(map[string]bool)
+	for _, rule := range rules {
+		if rule.RegionID != 0 {
+			overridden[rule.Kind+"/"+rule.Code] = true
+		}
+	}
+
+	result := make([]models.BonusRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.RegionID == 0 && overridden[rule.Kind+"/"+rule.Code] {
+			continue
+		}
+		result = append(result, rule)
+	}
+
+	return result
+}
+
+// Начисления пользователя по всем правилам за сумму его отчетов
+func calculatePayout(rules []models.BonusRule, total models.StatTotal) models.BonusPayout {
+	stat := total.Stat()
+	payout := models.BonusPayout{
+		Name:    total.Name,
+		Reports: total.Reports,
+		Lines:   make([]models.BonusLine, 0, len(rules)),
+	}
+
+	for _, rule := range rules {
+		line := models.BonusLine{Rule: rule.Name, Kind: rule.Kind, Code: rule.Code}
+
+		switch rule.Kind {
+		case models.BonusRulePlan:
+			if p := stat.Product(rule.Code); p != nil {
+				line.Plan, line.Fact = round2(p.Plan), round2(p.Fact)
+			}
+			line.Achievement = percent(line.Fact, line.Plan)
+
+			if tier := bonusTier(rule.Tiers, line.Achievement); tier != nil {
+				base := line.Fact
+				if rule.Base != nil {
+					base = *rule.Base
+				}
+				line.Rate = tier.Rate
+				line.Amount = round2(base*tier.Rate/100 + tier.Amount)
+			}
+		case models.BonusRuleMetric:
+			if m := stat.Metric(rule.Code); m != nil {
+				line.Value = m.Value
+			}
+			line.Amount = round2(float64(line.Value) * rule.PerUnit)
+		}
+
+		payout.Lines = append(payout.Lines, line)
+		payout.Total += line.Amount
+	}
+	payout.Total = round2(payout.Total)
+
+	return payout
+}
+
+// Ступень с наибольшим порогом, не превышающим процент выполнения. Без плана ступени нет
+func bonusTier(tiers []models.BonusTier, achievement *float64) *models.BonusTier {
+	if achievement == nil {
+		return nil
+	}
+
+	var result *models.BonusTier
+	for i := range tiers {
+		if tiers[i].From <= *achievement {
+			result = &tiers[i]
+		}
+	}
+	return result
+}
@@ -0,0 +1,346 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/repository"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/lib/holidays"
+	"github.com/Wladim1r/statcounter/internal/models"
+)
+
+// Календарь рабочих дней региона
+type WorkCalendar interface {
+	// Рабочие дни региона с from по to включительно, ключ - дата YYYY-MM-DD
+	WorkingDays(regionID uint, from, to time.Time) (map[string]bool, error)
+}
+
+// Выходные, если рабочая неделя не настроена: суббота и воскресенье
+var defaultWeekends = []int{6, 7}
+
+type WorkWeekRequest struct {
+	RegionID uint  `json:"region_id"` // 0 - для всех регионов
+	Weekends []int `json:"weekends"`
+}
+
+type CalendarDayRequest struct {
+	RegionID uint   `json:"region_id"` // 0 - для всех регионов
+	Date     string `json:"date"      binding:"required"`
+	Working  bool   `json:"working"`
+	Name     string `json:"name"`
+}
+
+// Календарь региона за период
+type RegionCalendar struct {
+	RegionID    uint                 `json:"region_id"`
+	From        string               `json:"from"`
+	To          string               `json:"to"`
+	Weekends    []int                `json:"weekends"`
+	Days        []models.CalendarDay `json:"days"`
+	WorkingDays []string             `json:"working_days"`
+}
+
+type CalendarService interface {
+	WorkCalendar
+	IsWorkingDay(regionID uint, date time.Time) (bool, error)
+	GetCalendar(regionID uint, from, to time.Time) (*RegionCalendar, error)
+	SetWeekends(req WorkWeekRequest, actor string) (*models.WorkWeek, error)
+	SaveDays(days []CalendarDayRequest, actor string) error
+	DeleteDay(id uint) error
+	// Импорт праздников из CSV или iCal, возвращает число сохраненных дней
+	ImportHolidays(regionID uint, filename string, data []byte, actor string) (int, error)
+}
+
+type calendarService struct {
+	repo    repository.CalendarRepo
+	regions RegionLister
+}
+
+func NewCalendarService(repo repository.CalendarRepo, regions RegionLister) CalendarService {
+	return &calendarService{repo: repo, regions: regions}
+}
+
+func (s *calendarService) WorkingDays(regionID uint, from, to time.Time) (map[string]bool, error) {
+	weekends, err := s.weekends(regionID)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides, err := s.overrides(regionID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	days := make(map[string]bool)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+
+		working := !weekends[isoWeekday(day)]
+		if override, ok := overrides[key]; ok {
+			working = override.Working
+		}
+		if working {
+			days[key] = true
+		}
+	}
+
+	return days, nil
+}
+
+func (s *calendarService) IsWorkingDay(regionID uint, date time.Time) (bool, error) {
+	days, err := s.WorkingDays(regionID, date, date)
+	if err != nil {
+		return false, err
+	}
+
+	return days[date.Format("2006-01-02")], nil
+}
+
+func (s *calendarService) GetCalendar(regionID uint, from, to time.Time) (*RegionCalendar, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: period end is before its start", errs.ErrBadRequest)
+	}
+	if err := s.checkRegion(regionID); err != nil {
+		return nil, err
+	}
+
+	weekends, err := s.weekends(regionID)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides, err := s.overrides(regionID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	working, err := s.WorkingDays(regionID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := &RegionCalendar{
+		RegionID:    regionID,
+		From:        from.Format("2006-01-02"),
+		To:          to.Format("2006-01-02"),
+		Days:        make([]models.CalendarDay, 0, len(overrides)),
+		WorkingDays: make([]string, 0, len(working)),
+	}
+	for weekday := 1; weekday <= 7; weekday++ {
+		if weekends[weekday] {
+			calendar.Weekends = append(calendar.Weekends, weekday)
+		}
+	}
+	for _, day := range overrides {
+		calendar.Days = append(calendar.Days, day)
+	}
+	sort.Slice(calendar.Days, func(i, j int) bool {
+		return calendar.Days[i].Date < calendar.Days[j].Date
+	})
+	for day := range working {
+		calendar.WorkingDays = append(calendar.WorkingDays, day)
+	}
+	sort.Strings(calendar.WorkingDays)
+
+	return calendar, nil
+}
+
+func (s *calendarService) SetWeekends(req WorkWeekRequest, actor string) (*models.WorkWeek, error) {
+	if err := s.checkRegion(req.RegionID); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int]bool)
+	weekends := make([]int, 0, len(req.Weekends))
+	for _, weekday := range req.Weekends {
+		if weekday < 1 || weekday > 7 {
+			return nil, fmt.Errorf("%w: weekday must be between 1 (Monday) and 7 (Sunday)", errs.ErrBadRequest)
+		}
+		if !seen[weekday] {
+			seen[weekday] = true
+			weekends = append(weekends, weekday)
+		}
+	}
+	if len(weekends) == 7 {
+		return nil, fmt.Errorf("%w: week must have at least one working day", errs.ErrBadRequest)
+	}
+	sort.Ints(weekends)
+
+	week := &models.WorkWeek{
+		RegionID:  req.RegionID,
+		Weekends:  weekends,
+		UpdatedBy: actor,
+		UpdatedAt: time.Now(),
+	}
+	if err := s.repo.SaveWorkWeek(week); err != nil {
+		return nil, err
+	}
+
+	return week, nil
+}
+
+func (s *calendarService) SaveDays(days []CalendarDayRequest, actor string) error {
+	if len(days) == 0 {
+		return fmt.Errorf("%w: no days to save", errs.ErrBadRequest)
+	}
+
+	checked := make(map[uint]bool)
+	result := make([]models.CalendarDay, 0, len(days))
+	for _, req := range days {
+		if !checked[req.RegionID] {
+			if err := s.checkRegion(req.RegionID); err != nil {
+				return err
+			}
+			checked[req.RegionID] = true
+		}
+
+		date, err := time.ParseInLocation("2006-01-02", req.Date, time.Local)
+		if err != nil {
+			return fmt.Errorf("%w: invalid date %q, use YYYY-MM-DD", errs.ErrBadRequest, req.Date)
+		}
+
+		result = append(result, models.CalendarDay{
+			RegionID: req.RegionID,
+			Date:     date.Format("2006-01-02"),
+			Working:  req.Working,
+			Name:     req.Name,
+		})
+	}
+
+	return s.repo.SaveCalendarDays(uniqueDays(result, actor))
+}
+
+func (s *calendarService) DeleteDay(id uint) error {
+	return s.repo.DeleteCalendarDay(id)
+}
+
+func (s *calendarService) ImportHolidays(
+	regionID uint,
+	filename string,
+	data []byte,
+	actor string,
+) (int, error) {
+	if err := s.checkRegion(regionID); err != nil {
+		return 0, err
+	}
+
+	parsed, err := holidays.Parse(filename, data)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errs.ErrBadRequest, err)
+	}
+	if len(parsed) == 0 {
+		return 0, fmt.Errorf("%w: file contains no days", errs.ErrBadRequest)
+	}
+
+	days := make([]models.CalendarDay, 0, len(parsed))
+	for _, holiday := range parsed {
+		days = append(days, models.CalendarDay{
+			RegionID: regionID,
+			Date:     holiday.Date.Format("2006-01-02"),
+			Working:  holiday.Working,
+			Name:     holiday.Name,
+		})
+	}
+
+	days = uniqueDays(days, actor)
+	if err := s.repo.SaveCalendarDays(days); err != nil {
+		return 0, err
+	}
+
+	return len(days), nil
+}
+
+// Выходные дни недели региона: своя настройка, затем общая, затем по умолчанию
+func (s *calendarService) weekends(regionID uint) (map[int]bool, error) {
+	weeks, err := s.repo.GetWorkWeeks(regionID)
+	if err != nil {
+		return nil, err
+	}
+
+	weekdays := defaultWeekends
+	for _, week := range weeks {
+		// Недели упорядочены по региону, поэтому настройка региона идет последней
+		weekdays = week.Weekends
+	}
+
+	result := make(map[int]bool, len(weekdays))
+	for _, weekday := range weekdays {
+		result[weekday] = true
+	}
+	return result, nil
+}
+
+// Особые дни периода, день региона заменяет общий день на ту же дату
+func (s *calendarService) overrides(
+	regionID uint,
+	from, to time.Time,
+) (map[string]models.CalendarDay, error) {
+	days, err := s.repo.GetCalendarDays(
+		regionID,
+		from.Format("2006-01-02"),
+		to.Format("2006-01-02"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]models.CalendarDay, len(days))
+	for _, day := range days {
+		if current, ok := result[day.Date]; ok && current.RegionID > day.RegionID {
+			continue
+		}
+		result[day.Date] = day
+	}
+	return result, nil
+}
+
+// RegionID 0 - общий календарь, иначе регион должен существовать
+func (s *calendarService) checkRegion(regionID uint) error {
+	if regionID == 0 {
+		return nil
+	}
+
+	regions, err := s.regions.GetRegions()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+	for _, region := range regions {
+		if region.ID == regionID {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: region %d not found", errs.ErrBadRequest, regionID)
+}
+
+// Одна запись на регион и дату (последняя побеждает): upsert не может изменить строку дважды
+func uniqueDays(days []models.CalendarDay, actor string) []models.CalendarDay {
+	index := make(map[string]int, len(days))
+	result := make([]models.CalendarDay, 0, len(days))
+	now := time.Now()
+
+	for _, day := range days {
+		day.UpdatedBy = actor
+		day.UpdatedAt = now
+
+		key := fmt.Sprintf("%d/%s", day.RegionID, day.Date)
+		if i, ok := index[key]; ok {
+			result[i] = day
+			continue
+		}
+		index[key] = len(result)
+		result = append(result, day)
+	}
+
+	return result
+}
+
+// День недели по ISO: 1 - понедельник ... 7 - воскресенье
+func isoWeekday(date time.Time) int {
+	if date.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(date.Weekday())
+}
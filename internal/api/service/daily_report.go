@@ -0,0 +1,239 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/repository"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/lib/pdf"
+	"github.com/Wladim1r/statcounter/internal/lib/retention"
+	"github.com/Wladim1r/statcounter/internal/models"
+)
+
+// Поля страницы сводки в пунктах
+const reportMargin = 36.0
+
+// Ежедневная сводка региона в PDF: отчеты пользователей, итоги региона и KPI-счетчики на одной странице
+type DailyReportService interface {
+	// PDF-сводка региона за дату (по умолчанию сегодня) и имя файла
+	RenderDailyReport(regionID uint, date string) ([]byte, string, error)
+}
+
+type dailyReportService struct {
+	stats    AdminStatsService
+	products repository.ProductRepo
+	metrics  repository.MetricRepo
+	policy   *retention.Policy
+	// nil - шрифт не загружен, PDF недоступен
+	font *pdf.Font
+}
+
+func NewDailyReportService(
+	stats AdminStatsService,
+	products repository.ProductRepo,
+	metrics repository.MetricRepo,
+	policy *retention.Policy,
+	font *pdf.Font,
+) DailyReportService {
+	return &dailyReportService{
+		stats:    stats,
+		products: products,
+		metrics:  metrics,
+		policy:   policy,
+		font:     font,
+	}
+}
+
+func (s *dailyReportService) RenderDailyReport(regionID uint, date string) ([]byte, string, error) {
+	if s.font == nil {
+		return nil, "", fmt.Errorf("%w: PDF font is not configured, set PDF_FONT_PATH", errs.ErrUnavailable)
+	}
+
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+	reportDate, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", errs.ErrBadRequest, "Invalid date format. Use YYYY-MM-DD")
+	}
+	if !s.policy.IsRetained(regionID, reportDate, time.Now()) {
+		return nil, "", fmt.Errorf(
+			"%w: date %s is older than %d days, use /djin/archive for earlier months",
+			errs.ErrBadRequest,
+			date,
+			s.policy.Days(regionID),
+		)
+	}
+
+	// Итоги региона берутся из агрегатора, как и в /admin/stats/regions/:id
+	reports, err := s.stats.GetRegionReports(regionID, date, "")
+	if err != nil {
+		return nil, "", err
+	}
+	sort.Slice(reports.Reports, func(i, j int) bool {
+		return reports.Reports[i].Name < reports.Reports[j].Name
+	})
+
+	total := reports.Total.Stat()
+	products, metrics, err := reportColumns(
+		s.products,
+		s.metrics,
+		regionID,
+		append([]models.StatDaily{total}, reports.Reports...),
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	doc := pdf.NewDocument(s.font, pdf.A4Height, pdf.A4Width)
+	page := doc.AddPage()
+	width, height := doc.Size()
+	x, y := reportMargin, reportMargin
+	contentWidth := width - 2*reportMargin
+
+	page.Text(x, y+16, 16, "Ежедневный отчет: "+reports.RegionName)
+	y += 24
+
+	subtitle := fmt.Sprintf("Дата: %s    Отчетов: %d", reportDate.Format("02.01.2006"), reports.Total.Reports)
+	if reports.Total.Score != nil {
+		subtitle += "    Оценка KPI: " + formatReportNumber(*reports.Total.Score)
+	}
+	page.Text(x, y+10, 10, subtitle)
+	page.TextRight(width-reportMargin, y+10, 8, "Сформирован: "+time.Now().Format("02.01.2006 15:04"))
+	y += 24
+
+	productTable := dailyProductTable(reports, products, contentWidth)
+	tables := []pdf.Table{productTable}
+	if len(metrics) > 0 {
+		tables = append(tables, dailyMetricTable(reports, metrics, contentWidth))
+	}
+
+	// Высота строк и размер шрифта подбираются так, чтобы все поместилось на одну страницу
+	const tableGap = 16.0
+	var lines int
+	for _, table := range tables {
+		lines += table.Lines()
+	}
+	available := height - reportMargin - y - tableGap*float64(len(tables)-1)
+	rowHeight := math.Min(16, available/float64(lines))
+	size := rowHeight * 0.6
+	for _, table := range tables {
+		size = math.Min(size, fitNumberSize(doc, table))
+	}
+
+	for i, table := range tables {
+		if i > 0 {
+			y += tableGap
+		}
+		y = page.Table(x, y, size, rowHeight, table)
+	}
+
+	data, err := doc.Bytes()
+	if err != nil {
+		return nil, "", fmt.Errorf("render pdf: %w", err)
+	}
+
+	return data, fmt.Sprintf("report_%d_%s.pdf", regionID, date), nil
+}
+
+// Таблица план/факт/разница по продуктам с оценкой KPI и итогом региона
+func dailyProductTable(reports *RegionReports, products []models.Product, width float64) pdf.Table {
+	table := pdf.Table{
+		Groups:  []pdf.ColumnGroup{{Span: 1}},
+		Columns: []pdf.Column{{Title: "Пользователь", Width: width * 0.2}},
+	}
+
+	numeric := len(products)*3 + 1
+	columnWidth := width * 0.8 / float64(numeric)
+	for _, p := range products {
+		table.Groups = append(table.Groups, pdf.ColumnGroup{Title: p.Name, Span: 3})
+		for _, title := range []string{"План", "Факт", "Разница"} {
+			table.Columns = append(table.Columns, pdf.Column{Title: title, Width: columnWidth, Align: pdf.AlignRight})
+		}
+	}
+	table.Groups = append(table.Groups, pdf.ColumnGroup{Span: 1})
+	table.Columns = append(table.Columns, pdf.Column{Title: "Оценка", Width: columnWidth, Align: pdf.AlignRight})
+
+	row := func(name string, stat models.StatDaily, score *float64) []string {
+		cells := []string{name}
+		for _, product := range products {
+			if p := stat.Product(product.Code); p != nil {
+				cells = append(cells, formatReportNumber(p.Plan), formatReportNumber(p.Fact), formatReportNumber(p.Dif))
+			} else {
+				cells = append(cells, "", "", "")
+			}
+		}
+		if score != nil {
+			return append(cells, formatReportNumber(*score))
+		}
+		return append(cells, "")
+	}
+
+	for _, stat := range reports.Reports {
+		table.Rows = append(table.Rows, row(stat.Name, stat, stat.Score))
+	}
+	table.Footer = row("Итого по региону", reports.Total.Stat(), reports.Total.Score)
+
+	return table
+}
+
+// Таблица KPI-счетчиков с итогом региона
+func dailyMetricTable(reports *RegionReports, metrics []models.MetricDefinition, width float64) pdf.Table {
+	table := pdf.Table{
+		Groups: []pdf.ColumnGroup{
+			{Span: 1},
+			{Title: "KPI-счетчики", Span: len(metrics)},
+		},
+		Columns: []pdf.Column{{Title: "Пользователь", Width: width * 0.2}},
+	}
+
+	columnWidth := width * 0.8 / float64(len(metrics))
+	for _, m := range metrics {
+		table.Columns = append(table.Columns, pdf.Column{Title: m.Name, Width: columnWidth, Align: pdf.AlignRight})
+	}
+
+	row := func(name string, stat models.StatDaily) []string {
+		cells := []string{name}
+		for _, def := range metrics {
+			if m := stat.Metric(def.Code); m != nil {
+				cells = append(cells, strconv.Itoa(m.Value))
+			} else {
+				cells = append(cells, "")
+			}
+		}
+		return cells
+	}
+
+	for _, stat := range reports.Reports {
+		table.Rows = append(table.Rows, row(stat.Name, stat))
+	}
+	table.Footer = row("Итого по региону", reports.Total.Stat())
+
+	return table
+}
+
+// Наибольший размер шрифта, при котором числа помещаются в столбцы без обрезки
+func fitNumberSize(doc *pdf.Document, table pdf.Table) float64 {
+	size := math.Inf(1)
+
+	for _, cells := range append(table.Rows, table.Footer) {
+		for i, cell := range cells {
+			if i >= len(table.Columns) || table.Columns[i].Align != pdf.AlignRight || cell == "" {
+				continue
+			}
+			// Отступы ячейки - по трети размера шрифта с каждой стороны
+			unit := doc.TextWidth(cell, 1) + 2.0/3
+			size = math.Min(size, table.Columns[i].Width/unit)
+		}
+	}
+
+	return size
+}
+
+func formatReportNumber(v float64) string {
+	return strconv.FormatFloat(round2(v), 'f', -1, 64)
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/repository"
+	"github.com/Wladim1r/statcounter/internal/auth"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/lib/notify"
+	"github.com/Wladim1r/statcounter/internal/models"
+)
+
+// Итоговая сводка региона за день
+type Digest struct {
+	RegionID   uint             `json:"region_id"`
+	RegionName string           `json:"region_name"`
+	Date       string           `json:"date"`
+	Total      models.StatTotal `json:"total"`
+	// false - день нерабочий, отчеты не ожидались
+	WorkingDay bool `json:"working_day"`
+	// Сколько отчетов ожидалось: активные представители региона
+	Expected int `json:"expected"`
+	// Пользователи, не сдавшие отчет
+	Missing []string `json:"missing"`
+	// Текст сводки для почты и Telegram
+	Text string `json:"text"`
+}
+
+// Результат рассылки: сводка и записи журнала по каждому получателю и каналу
+type DigestResult struct {
+	Digest     *Digest                  `json:"digest"`
+	Deliveries []models.NotificationLog `json:"deliveries"`
+}
+
+type DigestService interface {
+	ComposeDigest(regionID uint, date string) (*Digest, error)
+	// Отправить сводку администраторам региона по всем настроенным каналам.
+	// Уже доставленные сводки повторно не отправляются, если не указан force
+	SendDigest(ctx context.Context, regionID uint, date string, force bool) (*DigestResult, error)
+	GetNotifications(regionID uint, date string, kind string) ([]models.NotificationLog, error)
+}
+
+type digestService struct {
+	stats    AdminStatsService
+	reports  DailyReportService
+	missing  MissingService
+	products repository.ProductRepo
+	metrics  repository.MetricRepo
+	users    UserLister
+	log      repository.NotificationRepo
+	sender   *notificationSender
+}
+
+func NewDigestService(
+	stats AdminStatsService,
+	reports DailyReportService,
+	missing MissingService,
+	products repository.ProductRepo,
+	metrics repository.MetricRepo,
+	users UserLister,
+	log repository.NotificationRepo,
+	notifiers []notify.Notifier,
+) DigestService {
+	return &digestService{
+		stats:    stats,
+		reports:  reports,
+		missing:  missing,
+		products: products,
+		metrics:  metrics,
+		users:    users,
+		log:      log,
+		sender:   &notificationSender{log: log, notifiers: notifiers},
+	}
+}
+
+func (s *digestService) ComposeDigest(regionID uint, date string) (*Digest, error) {
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+
+	reports, err := s.stats.GetRegionReports(regionID, date, "")
+	if err != nil {
+		return nil, err
+	}
+
+	missing, err := s.missing.GetMissing(regionID, date)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := &Digest{
+		RegionID:   regionID,
+		RegionName: reports.RegionName,
+		Date:       date,
+		Total:      reports.Total,
+		WorkingDay: missing.WorkingDay,
+		Expected:   missing.Expected,
+		Missing:    missing.Missing,
+	}
+
+	total := reports.Total.Stat()
+	products, metrics, err := reportColumns(s.products, s.metrics, regionID, []models.StatDaily{total})
+	if err != nil {
+		return nil, err
+	}
+	digest.Text = digestText(digest, total, products, metrics)
+
+	return digest, nil
+}
+
+func (s *digestService) SendDigest(
+	ctx context.Context,
+	regionID uint,
+	date string,
+	force bool,
+) (*DigestResult, error) {
+	if err := s.sender.configured(); err != nil {
+		return nil, err
+	}
+
+	digest, err := s.ComposeDigest(regionID, date)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := notify.Message{
+		Subject: fmt.Sprintf("Сводка за %s: %s", formatDigestDate(digest.Date), digest.RegionName),
+		Text:    digest.Text,
+	}
+
+	// PDF прикладывается, если на сервере настроен шрифт, иначе уходит только текст
+	data, filename, err := s.reports.RenderDailyReport(regionID, digest.Date)
+	switch {
+	case err == nil:
+		msg.Attachments = append(msg.Attachments, notify.Attachment{
+			Filename:    filename,
+			ContentType: "application/pdf",
+			Data:        data,
+		})
+	case errors.Is(err, errs.ErrUnavailable):
+		log.Printf("digest for region %d is sent without PDF: %v", regionID, err)
+	default:
+		return nil, err
+	}
+
+	admins, err := regionUsers(s.users, regionID, auth.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.sender.deliverAll(ctx, models.NotificationDigest, regionID, digest.Date, admins, msg, force)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DigestResult{Digest: digest, Deliveries: deliveries}, nil
+}
+
+func (s *digestService) GetNotifications(
+	regionID uint,
+	date string,
+	kind string,
+) ([]models.NotificationLog, error) {
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrBadRequest, "Invalid date format. Use YYYY-MM-DD")
+	}
+
+	return s.log.GetNotifications(regionID, date, kind)
+}
+
+// Текст сводки: итоги по продуктам, KPI-счетчики, оценка и список не сдавших отчет
+func digestText(
+	digest *Digest,
+	total models.StatDaily,
+	products []models.Product,
+	metrics []models.MetricDefinition,
+) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Сводка за %s: %s\n", formatDigestDate(digest.Date), digest.RegionName)
+	if digest.WorkingDay {
+		fmt.Fprintf(&b, "Отчетов: %d из %d\n", digest.Total.Reports, digest.Expected)
+	} else {
+		fmt.Fprintf(&b, "Отчетов: %d, день нерабочий\n", digest.Total.Reports)
+	}
+
+	if len(products) > 0 {
+		b.WriteString("\nПродукты:\n")
+		for _, product := range products {
+			p := total.Product(product.Code)
+			if p == nil {
+				p = &models.StatProduct{}
+			}
+			fmt.Fprintf(&b, "• %s: план %s, факт %s", product.Name, formatReportNumber(p.Plan), formatReportNumber(p.Fact))
+			if pct := percent(p.Fact, p.Plan); pct != nil {
+				fmt.Fprintf(&b, " (%s%%)", formatReportNumber(*pct))
+			}
+			fmt.Fprintf(&b, ", разница %s\n", formatReportNumber(p.Dif))
+		}
+	}
+
+	if len(metrics) > 0 {
+		b.WriteString("\nKPI-счетчики:\n")
+		for _, def := range metrics {
+			value := 0
+			if m := total.Metric(def.Code); m != nil {
+				value = m.Value
+			}
+			fmt.Fprintf(&b, "• %s: %d\n", def.Name, value)
+		}
+	}
+
+	if digest.Total.Score != nil {
+		fmt.Fprintf(&b, "\nОценка KPI: %s\n", formatReportNumber(*digest.Total.Score))
+	}
+
+	switch {
+	case len(digest.Missing) > 0:
+		fmt.Fprintf(&b, "\nНе сдали отчет (%d): %s\n", len(digest.Missing), strings.Join(digest.Missing, ", "))
+	case digest.WorkingDay:
+		b.WriteString("\nВсе отчеты сданы\n")
+	}
+
+	return b.String()
+}
+
+func formatDigestDate(date string) string {
+	if t, err := time.Parse("2006-01-02", date); err == nil {
+		return t.Format("02.01.2006")
+	}
+	return date
+}
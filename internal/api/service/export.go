@@ -0,0 +1,185 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/repository"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/lib/export"
+	"github.com/Wladim1r/statcounter/internal/lib/summa"
+	"github.com/Wladim1r/statcounter/internal/models"
+)
+
+// Наибольший период выгрузки в днях
+const maxExportDays = 366
+
+type ExportQuery struct {
+	RegionID uint
+	// Пустое - отчеты всех пользователей региона
+	Name string
+	// Дата выгрузки либо период From..To, по умолчанию сегодня
+	Date string
+	From string
+	To   string
+}
+
+func (s *djnService) ExportStats(query ExportQuery) (*export.Table, string, error) {
+	from, to := query.From, query.To
+	switch {
+	case query.Date != "":
+		from, to = query.Date, query.Date
+	case from == "" && to == "":
+		from = time.Now().Format("2006-01-02")
+		to = from
+	}
+
+	fromDate, toDate, err := s.parseRange(query.RegionID, from, to)
+	if err != nil {
+		return nil, "", err
+	}
+	if toDate.Sub(fromDate) >= maxExportDays*24*time.Hour {
+		return nil, "", fmt.Errorf("%w: period must not exceed %d days", errs.ErrBadRequest, maxExportDays)
+	}
+
+	stats, err := s.repo.GetStatsByPeriod(query.RegionID, from, to)
+	if err != nil {
+		return nil, "", err
+	}
+	if query.Name != "" {
+		own := stats[:0]
+		for _, stat := range stats {
+			if stat.Name == query.Name {
+				own = append(own, stat)
+			}
+		}
+		stats = own
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Date != stats[j].Date {
+			return stats[i].Date < stats[j].Date
+		}
+		return stats[i].Name < stats[j].Name
+	})
+
+	// Итог пользователя считается по его отчетам, итог региона - тем же агрегатором, что и /djin/total
+	var total models.StatDaily
+	var reports int
+	if query.Name != "" {
+		total, reports = summa.Sum(stats...), len(stats)
+	} else {
+		var days []models.StatDaily
+		for day := fromDate; !day.After(toDate); day = day.AddDate(0, 0, 1) {
+			stat, quantity, err := s.repo.GetRegionTotals(query.RegionID, day.Format("2006-01-02"))
+			if err != nil {
+				return nil, "", err
+			}
+			days = append(days, stat)
+			reports += quantity
+		}
+		total = summa.Sum(days...)
+	}
+
+	products, metrics, err := reportColumns(
+		s.products,
+		s.metrics,
+		query.RegionID,
+		append([]models.StatDaily{total}, stats...),
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	table := &export.Table{Header: []string{"Дата", "Пользователь"}}
+	for _, p := range products {
+		table.Header = append(table.Header, p.Name+": план", p.Name+": факт", p.Name+": разница")
+	}
+	for _, m := range metrics {
+		table.Header = append(table.Header, m.Name)
+	}
+
+	for _, stat := range stats {
+		row := []any{stat.Date[:len("2006-01-02")], stat.Name}
+		table.Rows = append(table.Rows, append(row, exportValues(stat, products, metrics)...))
+	}
+
+	footer := []any{"Итого", fmt.Sprintf("Отчетов: %d", reports)}
+	table.Footer = append(table.Footer, append(footer, exportValues(total, products, metrics)...))
+
+	filename := fmt.Sprintf("stats_%d_%s_%s", query.RegionID, from, to)
+	if query.Name != "" {
+		filename += "_user"
+	}
+
+	return table, filename, nil
+}
+
+// Продукты каталога и счетчики региона: активные и те, что встречаются в отчетах
+func reportColumns(
+	productRepo repository.ProductRepo,
+	metricRepo repository.MetricRepo,
+	regionID uint,
+	stats []models.StatDaily,
+) ([]models.Product, []models.MetricDefinition, error) {
+	usedProducts := make(map[string]bool)
+	usedMetrics := make(map[string]bool)
+	for _, stat := range stats {
+		for _, p := range stat.Products {
+			usedProducts[p.Code] = true
+		}
+		for _, m := range stat.Metrics {
+			usedMetrics[m.Code] = true
+		}
+	}
+
+	catalog, err := productRepo.GetProducts(false)
+	if err != nil {
+		return nil, nil, err
+	}
+	var products []models.Product
+	for _, p := range catalog {
+		if p.Active || usedProducts[p.Code] {
+			products = append(products, p)
+		}
+	}
+
+	defs, err := metricRepo.GetMetricDefinitions(regionID, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	var metrics []models.MetricDefinition
+	for _, def := range effectiveMetrics(defs) {
+		if def.Active || usedMetrics[def.Code] {
+			metrics = append(metrics, def)
+		}
+	}
+
+	return products, metrics, nil
+}
+
+// Значения отчета по столбцам выгрузки, отсутствующие в отчете значения пустые
+func exportValues(
+	stat models.StatDaily,
+	products []models.Product,
+	metrics []models.MetricDefinition,
+) []any {
+	values := make([]any, 0, len(products)*3+len(metrics))
+
+	for _, product := range products {
+		if p := stat.Product(product.Code); p != nil {
+			values = append(values, p.Plan, p.Fact, p.Dif)
+		} else {
+			values = append(values, nil, nil, nil)
+		}
+	}
+	for _, def := range metrics {
+		if m := stat.Metric(def.Code); m != nil {
+			values = append(values, m.Value)
+		} else {
+			values = append(values, nil)
+		}
+	}
+
+	return values
+}
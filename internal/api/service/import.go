@@ -0,0 +1,325 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/lib/export"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"gorm.io/gorm"
+)
+
+// Ошибки одной строки файла импорта
+type ImportRowError struct {
+	Row    int      `json:"row"`
+	Name   string   `json:"name,omitempty"`
+	Date   string   `json:"date,omitempty"`
+	Errors []string `json:"errors"`
+}
+
+// Результат импорта. При ошибках хотя бы в одной строке ничего не сохраняется
+type ImportResult struct {
+	DryRun   bool               `json:"dry_run"`
+	Rows     int                `json:"rows"`
+	Valid    int                `json:"valid"`
+	Imported int                `json:"imported"`
+	Errors   []ImportRowError   `json:"errors"`
+	Reports  []models.StatDaily `json:"reports"`
+}
+
+// Назначение столбца файла импорта
+type importColumn struct {
+	field string // date, name, plan, fact, metric или пусто (столбец пропускается)
+	code  string
+}
+
+func (s *djnService) ImportStats(
+	regionID uint,
+	filename string,
+	data []byte,
+	actor models.Actor,
+	dryRun bool,
+) (*ImportResult, error) {
+	rows, err := export.Read(filename, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrBadRequest, err)
+	}
+	if len(rows) < 2 {
+		return nil, fmt.Errorf("%w: file has no reports", errs.ErrBadRequest)
+	}
+
+	columns, err := s.importColumns(regionID, rows[0])
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{
+		DryRun:  dryRun,
+		Errors:  []ImportRowError{},
+		Reports: []models.StatDaily{},
+	}
+	regions := make(map[string]uint)
+	seen := make(map[string]int)
+
+	for i, row := range rows[1:] {
+		rowNum := i + 2
+		if skipImportRow(row) {
+			continue
+		}
+		result.Rows++
+
+		stat, rowErrs := parseImportRow(row, columns)
+		rowErr := ImportRowError{Row: rowNum, Name: stat.Name, Date: stat.Date, Errors: rowErrs}
+
+		if stat.Name != "" {
+			if err := s.checkImportUser(regionID, stat.Name, regions); err != nil {
+				if !errors.Is(err, errs.ErrBadRequest) {
+					return nil, err
+				}
+				rowErr.Errors = append(rowErr.Errors, err.Error())
+			}
+		}
+
+		if stat.Name != "" && stat.Date != "" {
+			key := stat.Name + "/" + stat.Date
+			if first, ok := seen[key]; ok {
+				rowErr.Errors = append(rowErr.Errors, fmt.Sprintf("duplicate of row %d", first))
+			} else {
+				seen[key] = rowNum
+			}
+		}
+
+		// Та же проверка и подготовка, что и при подаче отчета через PostStat
+		if len(rowErr.Errors) == 0 {
+			stat.RegionID = regionID
+			if err := s.prepareStat(&stat, actor); err != nil {
+				if errors.Is(err, errs.ErrDBOperation) {
+					return nil, err
+				}
+				rowErr.Errors = append(rowErr.Errors, err.Error())
+			}
+		}
+
+		if len(rowErr.Errors) > 0 {
+			result.Errors = append(result.Errors, rowErr)
+			continue
+		}
+
+		result.Valid++
+		result.Reports = append(result.Reports, stat)
+	}
+
+	if result.Rows == 0 {
+		return nil, fmt.Errorf("%w: file has no reports", errs.ErrBadRequest)
+	}
+	if dryRun || len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	stats := make([]*models.StatDaily, len(result.Reports))
+	for i := range result.Reports {
+		stats[i] = &result.Reports[i]
+	}
+	if err := s.repo.PostStats(stats, actor); err != nil {
+		return nil, err
+	}
+	result.Imported = len(stats)
+
+	return result, nil
+}
+
+// Сопоставить заголовки с полями отчета. Принимаются коды (date, name, <code>_plan,
+// <code>_fact, <code счетчика>) и русские заголовки выгрузки
+func (s *djnService) importColumns(regionID uint, header []string) ([]importColumn, error) {
+	names := map[string]importColumn{
+		"date":         {field: "date"},
+		"дата":         {field: "date"},
+		"name":         {field: "name"},
+		"пользователь": {field: "name"},
+	}
+
+	catalog, err := s.products.GetProducts(false)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range catalog {
+		code, title := strings.ToLower(p.Code), strings.ToLower(p.Name)
+		names[code+"_plan"] = importColumn{field: "plan", code: p.Code}
+		names[title+": план"] = importColumn{field: "plan", code: p.Code}
+		names[code+"_fact"] = importColumn{field: "fact", code: p.Code}
+		names[title+": факт"] = importColumn{field: "fact", code: p.Code}
+		// Разница всегда считается на сервере
+		names[code+"_dif"] = importColumn{}
+		names[title+": разница"] = importColumn{}
+	}
+
+	defs, err := s.metrics.GetMetricDefinitions(regionID, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, def := range effectiveMetrics(defs) {
+		names[strings.ToLower(def.Code)] = importColumn{field: "metric", code: def.Code}
+		names[strings.ToLower(def.Name)] = importColumn{field: "metric", code: def.Code}
+	}
+
+	columns := make([]importColumn, len(header))
+	found := make(map[string]bool)
+	for i, title := range header {
+		title = strings.ToLower(strings.TrimSpace(title))
+		if title == "" {
+			continue
+		}
+
+		column, ok := names[title]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown column %q", errs.ErrBadRequest, header[i])
+		}
+
+		key := column.field + "/" + column.code
+		if column.field != "" && found[key] {
+			return nil, fmt.Errorf("%w: duplicate column %q", errs.ErrBadRequest, header[i])
+		}
+		found[key] = true
+		columns[i] = column
+	}
+
+	if !found["date/"] || !found["name/"] {
+		return nil, fmt.Errorf("%w: date and name columns are required", errs.ErrBadRequest)
+	}
+
+	return columns, nil
+}
+
+// Проверить, что пользователь существует и относится к региону импорта
+func (s *djnService) checkImportUser(regionID uint, name string, regions map[string]uint) error {
+	userRegion, ok := regions[name]
+	if !ok {
+		user, err := s.users.GetUserByUsername(name)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: user %q does not exist", errs.ErrBadRequest, name)
+			}
+			return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+		}
+		userRegion = user.RegionID
+		regions[name] = userRegion
+	}
+
+	if userRegion != regionID {
+		return fmt.Errorf("%w: user %q belongs to region %d", errs.ErrBadRequest, name, userRegion)
+	}
+	return nil
+}
+
+// Пустые строки и итоговая строка выгрузки пропускаются
+func skipImportRow(row []string) bool {
+	for _, cell := range row {
+		cell = strings.TrimSpace(cell)
+		if cell == "" {
+			continue
+		}
+		return strings.EqualFold(cell, "итого")
+	}
+	return true
+}
+
+// Собрать отчет из строки файла, вернуть ошибки значений
+func parseImportRow(row []string, columns []importColumn) (models.StatDaily, []string) {
+	var stat models.StatDaily
+	var rowErrs []string
+	var badDate bool
+	products := make(map[string]int)
+
+	for i, column := range columns {
+		if column.field == "" || i >= len(row) {
+			continue
+		}
+		value := strings.TrimSpace(row[i])
+
+		switch column.field {
+		case "date":
+			date, err := parseImportDate(value)
+			if err != nil && value != "" {
+				rowErrs = append(rowErrs, err.Error())
+				badDate = true
+				continue
+			}
+			stat.Date = date
+		case "name":
+			stat.Name = value
+		case "plan", "fact":
+			if value == "" {
+				continue
+			}
+			number, err := parseImportNumber(value)
+			if err != nil {
+				rowErrs = append(rowErrs, fmt.Sprintf("%s_%s: %v", column.code, column.field, err))
+				continue
+			}
+
+			j, ok := products[column.code]
+			if !ok {
+				j = len(stat.Products)
+				products[column.code] = j
+				stat.Products = append(stat.Products, models.StatProduct{Code: column.code})
+			}
+			if column.field == "plan" {
+				stat.Products[j].Plan = number
+			} else {
+				stat.Products[j].Fact = number
+			}
+		case "metric":
+			if value == "" {
+				continue
+			}
+			number, err := parseImportNumber(value)
+			if err != nil || number != math.Trunc(number) {
+				rowErrs = append(rowErrs, fmt.Sprintf("%s: must be a non-negative integer", column.code))
+				continue
+			}
+			stat.Metrics = append(stat.Metrics, models.StatMetric{Code: column.code, Value: int(number)})
+		}
+	}
+
+	if stat.Name == "" {
+		rowErrs = append(rowErrs, "name is required")
+	}
+	if stat.Date == "" && !badDate {
+		rowErrs = append(rowErrs, "date is required")
+	}
+
+	return stat, rowErrs
+}
+
+// Дата YYYY-MM-DD, DD.MM.YYYY или серийный номер дня Excel
+func parseImportDate(value string) (string, error) {
+	for _, layout := range []string{"2006-01-02", "02.01.2006"} {
+		if date, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return date.Format("2006-01-02"), nil
+		}
+	}
+
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial > 0 {
+		excelEpoch := time.Date(1899, time.December, 30, 0, 0, 0, 0, time.Local)
+		return excelEpoch.AddDate(0, 0, int(serial)).Format("2006-01-02"), nil
+	}
+
+	return "", fmt.Errorf("invalid date %q, use YYYY-MM-DD", value)
+}
+
+// Неотрицательное число, десятичный разделитель - точка или запятая
+func parseImportNumber(value string) (float64, error) {
+	number, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	if number < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return number, nil
+}
@@ -0,0 +1,322 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/repository"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/lib/retention"
+	"github.com/Wladim1r/statcounter/internal/models"
+)
+
+// Показатель, по которому строится рейтинг
+const (
+	// Процент выполнения плана по продукту
+	RankByPercent = "percent"
+	// Суммарный факт по продукту
+	RankByFact = "fact"
+	// Сводная оценка KPI по формуле региона
+	RankByScore = "score"
+)
+
+// Причины, по которым пользователь не попал в рейтинг
+const (
+	UnrankedFewReports = "not enough reports"
+	UnrankedNoPlan     = "no plan"
+)
+
+// Причина, по которой регион пропущен в общем рейтинге
+const SkippedPurged = "period reports are no longer stored"
+
+type LeaderboardQuery struct {
+	// 0 - рейтинг по всем регионам
+	RegionID uint
+	// day, week или month, по умолчанию month
+	Period string
+	// Дата внутри периода, по умолчанию сегодня
+	Date string
+	// percent, fact или score, по умолчанию score
+	By string
+	// Код продукта, обязателен для percent и fact
+	Product string
+	// Минимальное число отчетов за период для попадания в рейтинг
+	MinReports int
+}
+
+type LeaderboardEntry struct {
+	// Место в рейтинге, при равенстве значений места совпадают (1, 2, 2, 4)
+	Rank       int      `json:"rank,omitempty"`
+	Name       string   `json:"name"`
+	RegionID   uint     `json:"region_id"`
+	RegionName string   `json:"region_name,omitempty"`
+	Reports    int      `json:"reports"`
+	Plan       *float64 `json:"plan,omitempty"`
+	Fact       *float64 `json:"fact,omitempty"`
+	Percent    *float64 `json:"percent,omitempty"`
+	Score      *float64 `json:"score,omitempty"`
+	// Значение показателя рейтинга
+	Value  *float64 `json:"value"`
+	Reason string   `json:"reason,omitempty"`
+}
+
+type Leaderboard struct {
+	RegionID   uint               `json:"region_id"`
+	Period     string             `json:"period"`
+	From       string             `json:"from"`
+	To         string             `json:"to"`
+	By         string             `json:"by"`
+	Product    string             `json:"product,omitempty"`
+	MinReports int                `json:"min_reports"`
+	Entries    []LeaderboardEntry `json:"entries"`
+	// Пользователи с отчетами, не вошедшие в рейтинг
+	Unranked []LeaderboardEntry `json:"unranked"`
+	// Регионы, пропущенные в общем рейтинге
+	Skipped []SkippedRegion `json:"skipped"`
+}
+
+// Регион, для которого рейтинг за период построить нельзя
+type SkippedRegion struct {
+	RegionID   uint   `json:"region_id"`
+	RegionName string `json:"region_name"`
+	Reason     string `json:"reason"`
+}
+
+type LeaderboardService interface {
+	GetLeaderboard(query LeaderboardQuery) (*Leaderboard, error)
+}
+
+type leaderboardService struct {
+	repo    repository.DjnRepo
+	regions RegionLister
+	scores  ScoringService
+	policy  *retention.Policy
+	archive ArchiveService
+}
+
+func NewLeaderboardService(
+	repo repository.DjnRepo,
+	regions RegionLister,
+	scores ScoringService,
+	policy *retention.Policy,
+	archive ArchiveService,
+) LeaderboardService {
+	return &leaderboardService{
+		repo:    repo,
+		regions: regions,
+		scores:  scores,
+		policy:  policy,
+		archive: archive,
+	}
+}
+
+func (s *leaderboardService) GetLeaderboard(query LeaderboardQuery) (*Leaderboard, error) {
+	if query.Period == "" {
+		query.Period = GroupMonth
+	}
+	if query.Period != GroupDay && query.Period != GroupWeek && query.Period != GroupMonth {
+		return nil, fmt.Errorf("%w: %v", errs.ErrBadRequest, "Period must be day, week or month")
+	}
+
+	if query.By == "" {
+		query.By = RankByScore
+	}
+	switch query.By {
+	case RankByPercent, RankByFact:
+		if query.Product == "" {
+			return nil, fmt.Errorf("%w: product is required to rank by %s", errs.ErrBadRequest, query.By)
+		}
+	case RankByScore:
+		query.Product = ""
+	default:
+		return nil, fmt.Errorf("%w: %v", errs.ErrBadRequest, "By must be percent, fact or score")
+	}
+
+	if query.MinReports < 0 {
+		return nil, fmt.Errorf("%w: %v", errs.ErrBadRequest, "Min reports must not be negative")
+	}
+
+	if query.Date == "" {
+		query.Date = time.Now().Format("2006-01-02")
+	}
+	date, err := time.ParseInLocation("2006-01-02", query.Date, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrBadRequest, "Invalid date format. Use YYYY-MM-DD")
+	}
+	_, from, to := periodBounds(date, query.Period)
+
+	regions, err := s.regions.GetRegions()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	result := &Leaderboard{
+		RegionID:   query.RegionID,
+		Period:     query.Period,
+		From:       from.Format("2006-01-02"),
+		To:         to.Format("2006-01-02"),
+		By:         query.By,
+		Product:    query.Product,
+		MinReports: query.MinReports,
+		Entries:    []LeaderboardEntry{},
+		Unranked:   []LeaderboardEntry{},
+		Skipped:    []SkippedRegion{},
+	}
+
+	found := false
+	for _, region := range regions {
+		if query.RegionID != 0 && region.ID != query.RegionID {
+			continue
+		}
+		found = true
+
+		totals, ok, err := s.periodTotals(region.ID, query.Period, from, result.From, result.To)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			// Один регион с коротким сроком хранения не должен ломать общий рейтинг
+			if query.RegionID != 0 {
+				return nil, fmt.Errorf(
+					"%w: Period start is older than %d days for region %s",
+					errs.ErrBadRequest,
+					s.policy.Days(region.ID),
+					region.Name,
+				)
+			}
+			result.Skipped = append(result.Skipped, SkippedRegion{
+				RegionID:   region.ID,
+				RegionName: region.Name,
+				Reason:     SkippedPurged,
+			})
+			continue
+		}
+
+		score, err := s.scores.Scorer(region.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range leaderboardEntries(region.ID, totals, query, score) {
+			if query.RegionID == 0 {
+				entry.RegionName = region.Name
+			}
+			if entry.Reason != "" {
+				result.Unranked = append(result.Unranked, entry)
+			} else {
+				result.Entries = append(result.Entries, entry)
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: region %d not found", errs.ErrBadRequest, query.RegionID)
+	}
+
+	rankEntries(result.Entries)
+	sort.SliceStable(result.Unranked, func(i, j int) bool {
+		return result.Unranked[i].Name < result.Unranked[j].Name
+	})
+
+	return result, nil
+}
+
+// Итоги пользователей региона за период. Удаленные по сроку хранения дни месяца
+// берутся из месячных сводок; для дня и недели их восполнить нечем, тогда ok = false
+func (s *leaderboardService) periodTotals(
+	regionID uint,
+	period string,
+	start time.Time,
+	from string,
+	to string,
+) ([]models.StatTotal, bool, error) {
+	if s.policy.IsRetained(regionID, start, time.Now()) {
+		stats, err := s.repo.GetStatsByPeriod(regionID, from, to)
+		if err != nil {
+			return nil, false, err
+		}
+		return userTotals(regionID, stats), true, nil
+	}
+
+	if period != GroupMonth {
+		return nil, false, nil
+	}
+
+	totals, err := monthlyUserTotals(s.archive, regionID, start, "")
+	if err != nil {
+		return nil, false, err
+	}
+	return totals, true, nil
+}
+
+// Итоги пользователей региона за период с показателем рейтинга
+func leaderboardEntries(
+	regionID uint,
+	totals []models.StatTotal,
+	query LeaderboardQuery,
+	score Scorer,
+) []LeaderboardEntry {
+	var entries []LeaderboardEntry
+
+	for _, total := range totals {
+		stat := total.Stat()
+		entry := LeaderboardEntry{
+			Name:     total.Name,
+			RegionID: regionID,
+			Reports:  total.Reports,
+		}
+
+		if query.Product != "" {
+			var plan, fact float64
+			if p := stat.Product(query.Product); p != nil {
+				plan, fact = p.Plan, p.Fact
+			}
+			plan, fact = round2(plan), round2(fact)
+			entry.Plan, entry.Fact = &plan, &fact
+			entry.Percent = percent(fact, plan)
+		}
+		entry.Score = score(stat)
+
+		switch query.By {
+		case RankByPercent:
+			entry.Value = entry.Percent
+		case RankByFact:
+			entry.Value = entry.Fact
+		case RankByScore:
+			entry.Value = entry.Score
+		}
+
+		switch {
+		case entry.Reports < query.MinReports:
+			entry.Reason = UnrankedFewReports
+		case entry.Value == nil:
+			entry.Reason = UnrankedNoPlan
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// Отсортировать по убыванию значения и проставить места; равные значения делят место,
+// следующее место пропускается (1, 2, 2, 4)
+func rankEntries(entries []LeaderboardEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if *entries[i].Value != *entries[j].Value {
+			return *entries[i].Value > *entries[j].Value
+		}
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].RegionID < entries[j].RegionID
+	})
+
+	for i := range entries {
+		if i > 0 && *entries[i].Value == *entries[i-1].Value {
+			entries[i].Rank = entries[i-1].Rank
+			continue
+		}
+		entries[i].Rank = i + 1
+	}
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Wladim1r/statcounter/internal/api/repository"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+)
+
+type MetricRequest struct {
+	RegionID *uint  `json:"region_id,omitempty"`
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Required *bool  `json:"required,omitempty"`
+	Position *int   `json:"position,omitempty"`
+	Active   *bool  `json:"active,omitempty"`
+}
+
+type MetricService interface {
+	// Действующие счетчики региона: региональные переопределяют общие с тем же кодом
+	GetRegionMetrics(regionID uint, activeOnly bool) ([]models.MetricDefinition, error)
+	GetAllMetrics() ([]models.MetricDefinition, error)
+	CreateMetric(req MetricRequest) (*models.MetricDefinition, error)
+	UpdateMetric(id uint, req MetricRequest) (*models.MetricDefinition, error)
+	DeleteMetric(id uint) error
+}
+
+type metricService struct {
+	repo repository.MetricRepo
+}
+
+func NewMetricService(repo repository.MetricRepo) MetricService {
+	return &metricService{repo: repo}
+}
+
+func (s *metricService) GetRegionMetrics(
+	regionID uint,
+	activeOnly bool,
+) ([]models.MetricDefinition, error) {
+	defs, err := s.repo.GetMetricDefinitions(regionID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	defs = effectiveMetrics(defs)
+	if !activeOnly {
+		return defs, nil
+	}
+
+	active := make([]models.MetricDefinition, 0, len(defs))
+	for _, def := range defs {
+		if def.Active {
+			active = append(active, def)
+		}
+	}
+	return active, nil
+}
+
+func (s *metricService) GetAllMetrics() ([]models.MetricDefinition, error) {
+	return s.repo.GetAllMetricDefinitions()
+}
+
+func (s *metricService) CreateMetric(req MetricRequest) (*models.MetricDefinition, error) {
+	req.Code = strings.TrimSpace(req.Code)
+	req.Name = strings.TrimSpace(req.Name)
+
+	if !productCodeRe.MatchString(req.Code) || models.IsReservedMetricCode(req.Code) {
+		return nil, fmt.Errorf(
+			"%w: %v",
+			errs.ErrBadRequest,
+			"Metric code must contain only lowercase latin letters, digits and '_' "+
+				"and must not end with _plan, _fact or _dif",
+		)
+	}
+	if req.Name == "" {
+		return nil, fmt.Errorf("%w: %v", errs.ErrBadRequest, "Metric name is required")
+	}
+	if req.Type == "" {
+		req.Type = models.MetricTypeCounter
+	}
+	if !validMetricType(req.Type) {
+		return nil, fmt.Errorf("%w: %v", errs.ErrBadRequest, "Metric type must be counter or flag")
+	}
+
+	def := models.MetricDefinition{
+		Code:   req.Code,
+		Name:   req.Name,
+		Type:   req.Type,
+		Active: true,
+	}
+	if req.RegionID != nil {
+		def.RegionID = *req.RegionID
+	}
+	if req.Required != nil {
+		def.Required = *req.Required
+	}
+	if req.Position != nil {
+		def.Position = *req.Position
+	}
+	if req.Active != nil {
+		def.Active = *req.Active
+	}
+
+	if err := s.repo.CreateMetricDefinition(&def); err != nil {
+		return nil, err
+	}
+
+	return &def, nil
+}
+
+func (s *metricService) UpdateMetric(
+	id uint,
+	req MetricRequest,
+) (*models.MetricDefinition, error) {
+	// Код и регион неизменяемы: по ним хранятся значения в уже отправленных отчетах
+	if req.Code != "" || req.RegionID != nil {
+		return nil, fmt.Errorf(
+			"%w: %v",
+			errs.ErrBadRequest,
+			"Metric code and region cannot be changed",
+		)
+	}
+
+	updates := make(map[string]interface{})
+
+	if name := strings.TrimSpace(req.Name); name != "" {
+		updates["name"] = name
+	}
+	if req.Type != "" {
+		if !validMetricType(req.Type) {
+			return nil, fmt.Errorf("%w: %v", errs.ErrBadRequest, "Metric type must be counter or flag")
+		}
+		updates["type"] = req.Type
+	}
+	if req.Required != nil {
+		updates["required"] = *req.Required
+	}
+	if req.Position != nil {
+		updates["position"] = *req.Position
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+
+	return s.repo.UpdateMetricDefinition(id, updates)
+}
+
+func (s *metricService) DeleteMetric(id uint) error {
+	return s.repo.DeleteMetricDefinition(id)
+}
+
+func validMetricType(metricType string) bool {
+	return metricType == models.MetricTypeCounter || metricType == models.MetricTypeFlag
+}
+
+// Оставить по одному определению на код: региональное важнее общего
+func effectiveMetrics(defs []models.MetricDefinition) []models.MetricDefinition {
+	byCode := make(map[string]int, len(defs))
+	result := make([]models.MetricDefinition, 0, len(defs))
+
+	for _, def := range defs {
+		if i, ok := byCode[def.Code]; ok {
+			if def.RegionID != 0 {
+				result[i] = def
+			}
+			continue
+		}
+		byCode[def.Code] = len(result)
+		result = append(result, def)
+	}
+
+	return result
+}
+
+// Привести счетчики отчета к определениям региона: неизвестные коды отклоняются,
+// нулевые значения отключенных счетчиков отбрасываются, остальные проверяются по типу.
+// При fillMissing обязательные счетчики должны быть переданы,
+// а отсутствующие необязательные добавляются с нулевым значением
+func resolveMetrics(defs []models.MetricDefinition, stat *models.StatDaily, fillMissing bool) error {
+	byCode := make(map[string]models.MetricDefinition, len(defs))
+	for _, def := range defs {
+		byCode[def.Code] = def
+	}
+
+	submitted := stat.Metrics[:0]
+	for _, m := range stat.Metrics {
+		def, ok := byCode[m.Code]
+		if !ok {
+			// Любое числовое поле отчета считается счетчиком, поэтому называем его как поле
+			return fmt.Errorf("%w: unknown field %s: no such product or metric", errs.ErrBadRequest, m.Code)
+		}
+		if !def.Active {
+			if m.Value == 0 {
+				continue
+			}
+			return fmt.Errorf("%w: metric %s is inactive", errs.ErrBadRequest, m.Code)
+		}
+
+		switch def.Type {
+		case models.MetricTypeFlag:
+			if m.Value != 0 && m.Value != 1 {
+				return fmt.Errorf("%w: metric %s must be 0 or 1", errs.ErrBadRequest, m.Code)
+			}
+		default:
+			if m.Value < 0 {
+				return fmt.Errorf("%w: metric %s must not be negative", errs.ErrBadRequest, m.Code)
+			}
+		}
+		submitted = append(submitted, m)
+	}
+	stat.Metrics = submitted
+
+	if !fillMissing {
+		return nil
+	}
+
+	for _, def := range defs {
+		if !def.Active || stat.Metric(def.Code) != nil {
+			continue
+		}
+		if def.Required {
+			return fmt.Errorf("%w: metric %s is required", errs.ErrBadRequest, def.Code)
+		}
+		stat.Metrics = append(stat.Metrics, models.StatMetric{Code: def.Code})
+	}
+
+	return nil
+}
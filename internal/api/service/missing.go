@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/repository"
+	"github.com/Wladim1r/statcounter/internal/auth"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/lib/notify"
+	"github.com/Wladim1r/statcounter/internal/lib/retention"
+	"github.com/Wladim1r/statcounter/internal/lib/schedule"
+	"github.com/Wladim1r/statcounter/internal/models"
+)
+
+// Этапы напоминаний о несданных отчетах
+const (
+	// Время напоминаний еще не наступило, день нерабочий или срок не настроен
+	ReminderStageNone = "none"
+	// До срока: напоминания представителям, не сдавшим отчет
+	ReminderStageRemind = "reminder"
+	// После срока: сообщение администраторам региона
+	ReminderStageEscalate = "escalation"
+)
+
+// Кто из активных представителей региона не сдал отчет за рабочий день
+type MissingReports struct {
+	RegionID uint   `json:"region_id"`
+	Date     string `json:"date"`
+	// false - день нерабочий, отчеты не ожидаются
+	WorkingDay bool `json:"working_day"`
+	// Срок сдачи отчета HH:MM, пустой - срок не настроен
+	Deadline  string   `json:"deadline,omitempty"`
+	Expected  int      `json:"expected"`
+	Submitted int      `json:"submitted"`
+	Missing   []string `json:"missing"`
+}
+
+// Результат проверки напоминаний
+type ReminderResult struct {
+	Stage      string                   `json:"stage"`
+	Missing    *MissingReports          `json:"missing,omitempty"`
+	Deliveries []models.NotificationLog `json:"deliveries"`
+}
+
+type MissingService interface {
+	// Несданные отчеты региона за дату (по умолчанию сегодня)
+	GetMissing(regionID uint, date string) (*MissingReports, error)
+	// Разослать напоминания на момент now: за remindBefore до срока - представителям,
+	// после срока - администраторам региона. Каждое уведомление отправляется один раз в день
+	SendReminders(ctx context.Context, regionID uint, now time.Time) (*ReminderResult, error)
+}
+
+type missingService struct {
+	repo         repository.DjnRepo
+	users        UserLister
+	calendar     CalendarService
+	policy       *retention.Policy
+	deadlines    *schedule.Times
+	remindBefore time.Duration
+	sender       *notificationSender
+}
+
+func NewMissingService(
+	repo repository.DjnRepo,
+	users UserLister,
+	calendar CalendarService,
+	policy *retention.Policy,
+	deadlines *schedule.Times,
+	remindBefore time.Duration,
+	log repository.NotificationRepo,
+	notifiers []notify.Notifier,
+) MissingService {
+	return &missingService{
+		repo:         repo,
+		users:        users,
+		calendar:     calendar,
+		policy:       policy,
+		deadlines:    deadlines,
+		remindBefore: remindBefore,
+		sender:       &notificationSender{log: log, notifiers: notifiers},
+	}
+}
+
+func (s *missingService) GetMissing(regionID uint, date string) (*MissingReports, error) {
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+	day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrBadRequest, "Invalid date format. Use YYYY-MM-DD")
+	}
+	if !s.policy.IsRetained(regionID, day, time.Now()) {
+		return nil, fmt.Errorf(
+			"%w: date %s is older than %d days",
+			errs.ErrBadRequest,
+			date,
+			s.policy.Days(regionID),
+		)
+	}
+
+	return s.missing(regionID, day)
+}
+
+func (s *missingService) SendReminders(
+	ctx context.Context,
+	regionID uint,
+	now time.Time,
+) (*ReminderResult, error) {
+	result := &ReminderResult{Stage: ReminderStageNone, Deliveries: []models.NotificationLog{}}
+
+	deadline, ok := s.deadlines.At(regionID)
+	if !ok || now.Before(deadline.On(now).Add(-s.remindBefore)) {
+		return result, nil
+	}
+	if err := s.sender.configured(); err != nil {
+		return nil, err
+	}
+
+	missing, err := s.missing(regionID, now)
+	if err != nil {
+		return nil, err
+	}
+	result.Missing = missing
+	if !missing.WorkingDay || len(missing.Missing) == 0 {
+		return result, nil
+	}
+
+	date := formatDigestDate(missing.Date)
+
+	if now.Before(deadline.On(now)) {
+		result.Stage = ReminderStageRemind
+
+		users, err := regionUsers(s.users, regionID, auth.RoleUser)
+		if err != nil {
+			return nil, err
+		}
+		missingNames := make(map[string]bool, len(missing.Missing))
+		for _, name := range missing.Missing {
+			missingNames[name] = true
+		}
+		var recipients []auth.User
+		for _, user := range users {
+			if missingNames[user.Username] {
+				recipients = append(recipients, user)
+			}
+		}
+
+		msg := notify.Message{
+			Subject: fmt.Sprintf("Напоминание: отчет за %s", date),
+			Text:    fmt.Sprintf("Отчет за %s еще не сдан. Срок сдачи - %s.", date, deadline),
+		}
+		result.Deliveries, err = s.sender.deliverAll(
+			ctx, models.NotificationReminder, regionID, missing.Date, recipients, msg, false,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	}
+
+	result.Stage = ReminderStageEscalate
+
+	admins, err := regionUsers(s.users, regionID, auth.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := notify.Message{
+		Subject: fmt.Sprintf("Не сданы отчеты за %s", date),
+		Text: fmt.Sprintf(
+			"К сроку %s не сдали отчет за %s (%d из %d): %s",
+			deadline,
+			date,
+			len(missing.Missing),
+			missing.Expected,
+			strings.Join(missing.Missing, ", "),
+		),
+	}
+	result.Deliveries, err = s.sender.deliverAll(
+		ctx, models.NotificationEscalation, regionID, missing.Date, admins, msg, false,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Сравнить активных представителей региона с отчетами за день
+func (s *missingService) missing(regionID uint, day time.Time) (*MissingReports, error) {
+	date := day.Format("2006-01-02")
+	result := &MissingReports{RegionID: regionID, Date: date, Missing: []string{}}
+	if deadline, ok := s.deadlines.At(regionID); ok {
+		result.Deadline = deadline.String()
+	}
+
+	working, err := s.calendar.IsWorkingDay(regionID, day)
+	if err != nil {
+		return nil, err
+	}
+	result.WorkingDay = working
+	if !working {
+		return result, nil
+	}
+
+	users, err := regionUsers(s.users, regionID, auth.RoleUser)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.repo.GetStatsByPeriod(regionID, date, date)
+	if err != nil {
+		return nil, err
+	}
+	submitted := make(map[string]bool, len(stats))
+	for _, stat := range stats {
+		submitted[stat.Name] = true
+	}
+
+	result.Expected = len(users)
+	for _, user := range users {
+		if submitted[user.Username] {
+			result.Submitted++
+		} else {
+			result.Missing = append(result.Missing, user.Username)
+		}
+	}
+	sort.Strings(result.Missing)
+
+	return result, nil
+}
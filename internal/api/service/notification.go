@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Wladim1r/statcounter/internal/api/repository"
+	"github.com/Wladim1r/statcounter/internal/auth"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/lib/notify"
+	"github.com/Wladim1r/statcounter/internal/models"
+)
+
+// Источник списка пользователей (реализуется auth.AuthService)
+type UserLister interface {
+	GetAllUsers() ([]auth.User, error)
+}
+
+// Отправка уведомлений пользователям по настроенным каналам с записью в журнал
+type notificationSender struct {
+	log       repository.NotificationRepo
+	notifiers []notify.Notifier
+}
+
+func (s *notificationSender) configured() error {
+	if len(s.notifiers) == 0 {
+		return fmt.Errorf("%w: no notification channels configured", errs.ErrUnavailable)
+	}
+	return nil
+}
+
+// Отправить сообщение пользователю по всем каналам, где у него есть контакт,
+// отмечая каждую отправку в журнале. Ошибки доставки записываются в журнал, а не возвращаются
+func (s *notificationSender) deliver(
+	ctx context.Context,
+	kind string,
+	regionID uint,
+	date string,
+	user auth.User,
+	msg notify.Message,
+	force bool,
+) ([]models.NotificationLog, error) {
+	recipient := notify.Recipient{
+		Name:           user.Username,
+		Email:          user.Email,
+		TelegramChatID: user.TelegramChatID,
+	}
+
+	var entries []models.NotificationLog
+	for _, notifier := range s.notifiers {
+		address := notifier.Address(recipient)
+		if address == "" {
+			continue
+		}
+
+		entry := models.NotificationLog{
+			Kind:      kind,
+			RegionID:  regionID,
+			Date:      date,
+			Recipient: user.Username,
+			Channel:   notifier.Channel(),
+			Address:   address,
+		}
+		claimed, err := s.log.ClaimNotification(&entry, force)
+		if err != nil {
+			return nil, err
+		}
+
+		if claimed {
+			sendErr := notifier.Send(ctx, address, msg)
+			if sendErr != nil {
+				log.Printf("error sending %s to %s via %s: %v", kind, user.Username, entry.Channel, sendErr)
+			}
+			if err := s.log.FinishNotification(&entry, sendErr); err != nil {
+				return nil, err
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Отправить сообщение всем пользователям списка
+func (s *notificationSender) deliverAll(
+	ctx context.Context,
+	kind string,
+	regionID uint,
+	date string,
+	users []auth.User,
+	msg notify.Message,
+	force bool,
+) ([]models.NotificationLog, error) {
+	deliveries := []models.NotificationLog{}
+	for _, user := range users {
+		entries, err := s.deliver(ctx, kind, regionID, date, user, msg, force)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, entries...)
+	}
+	return deliveries, nil
+}
+
+// Пользователи региона с ролью role. Для представителей (RoleUser) - только активные
+func regionUsers(users UserLister, regionID uint, role string) ([]auth.User, error) {
+	all, err := users.GetAllUsers()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	var result []auth.User
+	for _, user := range all {
+		if user.RegionID != regionID || user.Role != role {
+			continue
+		}
+		if role == auth.RoleUser && !user.Active {
+			continue
+		}
+		result = append(result, user)
+	}
+
+	return result, nil
+}
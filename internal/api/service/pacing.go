@@ -0,0 +1,241 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/repository"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/lib/summa"
+	"github.com/Wladim1r/statcounter/internal/models"
+)
+
+// Источник месячного плана продукта в расчете темпа
+const (
+	// Месячные цели администратора
+	PacingPlanTarget = "target"
+	// Планы из отчетов, экстраполированные на месяц
+	PacingPlanReports = "reports"
+)
+
+// Темп выполнения плана по продукту с начала месяца
+type ProductPacing struct {
+	Code       string  `json:"code"`
+	Name       string  `json:"name"`
+	PlanSource string  `json:"plan_source"`
+	MonthPlan  float64 `json:"month_plan"`
+	PlanToDate float64 `json:"plan_to_date"`
+	FactToDate float64 `json:"fact_to_date"`
+	// Факт минус план с начала месяца
+	Dif float64 `json:"dif"`
+	// Процент выполнения плана с начала месяца и месячного плана
+	Achievement      *float64 `json:"achievement"`
+	MonthAchievement *float64 `json:"month_achievement"`
+	// Сколько осталось до месячного плана и сколько нужно в каждый оставшийся рабочий день
+	Remaining     float64  `json:"remaining"`
+	RequiredDaily *float64 `json:"required_daily"`
+	// Линейный прогноз факта на конец месяца по среднему за рабочий день
+	Forecast            float64  `json:"forecast"`
+	ForecastAchievement *float64 `json:"forecast_achievement"`
+}
+
+// Темп выполнения плана пользователя (Name) или региона (пустой Name) за месяц
+type Pacing struct {
+	RegionID      uint            `json:"region_id"`
+	Name          string          `json:"name,omitempty"`
+	Month         string          `json:"month"`
+	AsOf          string          `json:"as_of"`
+	Reports       int             `json:"reports"`
+	WorkingDays   int             `json:"working_days"`
+	ElapsedDays   int             `json:"elapsed_days"`
+	RemainingDays int             `json:"remaining_days"`
+	Products      []ProductPacing `json:"products"`
+}
+
+type PacingService interface {
+	// Темп за месяц YYYY-MM по состоянию на сегодня (для прошедших месяцев - на конец месяца)
+	GetPacing(regionID uint, name string, month string) (*Pacing, error)
+}
+
+type pacingService struct {
+	plans    repository.PlanRepo
+	products repository.ProductRepo
+	calendar WorkCalendar
+	archive  ArchiveService
+}
+
+func NewPacingService(
+	plans repository.PlanRepo,
+	products repository.ProductRepo,
+	calendar WorkCalendar,
+	archive ArchiveService,
+) PacingService {
+	return &pacingService{
+		plans:    plans,
+		products: products,
+		calendar: calendar,
+		archive:  archive,
+	}
+}
+
+func (s *pacingService) GetPacing(regionID uint, name string, month string) (*Pacing, error) {
+	start, err := parseMonth(month)
+	if err != nil {
+		return nil, err
+	}
+	end := start.AddDate(0, 1, -1)
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if start.After(today) {
+		return nil, fmt.Errorf("%w: month %s has not started yet", errs.ErrBadRequest, month)
+	}
+	asOf := end
+	if today.Before(end) {
+		asOf = today
+	}
+
+	workingDays, err := s.calendar.WorkingDays(regionID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	// Дни в начале месяца, удаленные по сроку хранения, берутся из месячных сводок.
+	// Отчетов позже сегодняшнего дня нет, поэтому итоги месяца совпадают с итогами на asOf
+	users, err := monthlyUserTotals(s.archive, regionID, start, name)
+	if err != nil {
+		return nil, err
+	}
+	var total models.StatDaily
+	reports := 0
+	for _, user := range users {
+		total = summa.Sum(total, user.Stat())
+		reports += user.Reports
+	}
+
+	monthTargets, err := s.plans.GetPlanTargets(regionID, start.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	targets := scopeTargets(monthTargets, name)
+
+	catalog, err := s.products.GetProducts(false)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Pacing{
+		RegionID: regionID,
+		Name:     name,
+		Month:    start.Format("2006-01"),
+		AsOf:     asOf.Format("2006-01-02"),
+		Reports:  reports,
+		Products: []ProductPacing{},
+	}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if !workingDays[day.Format("2006-01-02")] {
+			continue
+		}
+		result.WorkingDays++
+		if !day.After(asOf) {
+			result.ElapsedDays++
+		}
+	}
+	result.RemainingDays = result.WorkingDays - result.ElapsedDays
+
+	for _, product := range catalog {
+		reported := total.Product(product.Code)
+		productTargets := targets[product.Code]
+
+		// Неактивный продукт без отчетов и целей в месяце не показываем
+		if !product.Active && reported == nil && len(productTargets) == 0 {
+			continue
+		}
+
+		pacing := ProductPacing{Code: product.Code, Name: product.Name}
+		if reported != nil {
+			pacing.FactToDate = reported.Fact
+		}
+
+		if len(productTargets) > 0 {
+			pacing.PlanSource = PacingPlanTarget
+			for _, target := range productTargets {
+				pacing.MonthPlan += target.Amount
+				pacing.PlanToDate += planShare(target, workingDays, start, start, asOf)
+			}
+		} else {
+			pacing.PlanSource = PacingPlanReports
+			if reported != nil {
+				pacing.PlanToDate = reported.Plan
+			}
+			pacing.MonthPlan = extrapolate(pacing.PlanToDate, result.ElapsedDays, result.WorkingDays)
+		}
+
+		pacing.Forecast = extrapolate(pacing.FactToDate, result.ElapsedDays, result.WorkingDays)
+		pacing.Remaining = math.Max(pacing.MonthPlan-pacing.FactToDate, 0)
+		if result.RemainingDays > 0 {
+			required := round2(pacing.Remaining / float64(result.RemainingDays))
+			pacing.RequiredDaily = &required
+		}
+
+		pacing.MonthPlan = round2(pacing.MonthPlan)
+		pacing.PlanToDate = round2(pacing.PlanToDate)
+		pacing.FactToDate = round2(pacing.FactToDate)
+		pacing.Dif = round2(pacing.FactToDate - pacing.PlanToDate)
+		pacing.Remaining = round2(pacing.Remaining)
+		pacing.Forecast = round2(pacing.Forecast)
+		pacing.Achievement = percent(pacing.FactToDate, pacing.PlanToDate)
+		pacing.MonthAchievement = percent(pacing.FactToDate, pacing.MonthPlan)
+		pacing.ForecastAchievement = percent(pacing.Forecast, pacing.MonthPlan)
+
+		result.Products = append(result.Products, pacing)
+	}
+
+	return result, nil
+}
+
+// Цели по коду продукта: цели пользователя, а для региона - цель региона
+// или, если ее нет, сумма целей пользователей региона
+func scopeTargets(targets []models.PlanTarget, name string) map[string][]models.PlanTarget {
+	own := make(map[string][]models.PlanTarget)
+	users := make(map[string][]models.PlanTarget)
+
+	for _, target := range targets {
+		switch {
+		case target.Name == name:
+			own[target.Code] = append(own[target.Code], target)
+		case name == "":
+			users[target.Code] = append(users[target.Code], target)
+		}
+	}
+
+	for code, userTargets := range users {
+		if _, ok := own[code]; !ok {
+			own[code] = userTargets
+		}
+	}
+
+	return own
+}
+
+// Линейная экстраполяция значения за прошедшие рабочие дни на весь месяц
+func extrapolate(value float64, elapsed, total int) float64 {
+	if elapsed == 0 {
+		return value
+	}
+	return value / float64(elapsed) * float64(total)
+}
+
+// Процент value от base, nil при нулевой базе
+func percent(value, base float64) *float64 {
+	if base == 0 {
+		return nil
+	}
+	result := round2(value / base * 100)
+	return &result
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/lib/summa"
+	"github.com/Wladim1r/statcounter/internal/models"
+)
+
+// Группировка отчетов за период
+const (
+	GroupDay   = "day"
+	GroupWeek  = "week"
+	GroupMonth = "month"
+)
+
+// Наибольшая длина периода в днях
+const maxPeriodDays = 366
+
+// Итоги одной группы (дня, ISO-недели или месяца)
+type StatGroup struct {
+	Key   string             `json:"key"`
+	From  string             `json:"from"`
+	To    string             `json:"to"`
+	Total models.StatTotal   `json:"total"`
+	Users []models.StatTotal `json:"users"`
+}
+
+// Итоги за период с разбивкой по группам
+type RangeStats struct {
+	From   string           `json:"from"`
+	To     string           `json:"to"`
+	Group  string           `json:"group"`
+	Total  models.StatTotal `json:"total"`
+	Groups []StatGroup      `json:"groups"`
+}
+
+func (s *djnService) GetStatsByRange(
+	regionID uint,
+	username string,
+	from string,
+	to string,
+	group string,
+) (*RangeStats, error) {
+	fromDate, toDate, err := s.parseRange(regionID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// Конец периода мог быть ограничен сегодняшним днем
+	to = toDate.Format("2006-01-02")
+
+	if group == "" {
+		group = GroupDay
+	}
+	if group != GroupDay && group != GroupWeek && group != GroupMonth {
+		return nil, fmt.Errorf("%w: %v", errs.ErrBadRequest, "Group must be day, week or month")
+	}
+
+	stats, err := s.repo.GetStatsByPeriod(regionID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if username != "" {
+		own := stats[:0]
+		for _, stat := range stats {
+			if stat.Name == username {
+				own = append(own, stat)
+			}
+		}
+		stats = own
+	}
+
+	result := &RangeStats{
+		From:   from,
+		To:     to,
+		Group:  group,
+		Total:  totalOf(regionID, stats),
+		Groups: groupStats(regionID, stats, fromDate, toDate, group),
+	}
+
+	// Оценка периода считается по сумме отчетов, а не как сумма оценок за дни
+	score, err := s.scores.Scorer(regionID)
+	if err != nil {
+		return nil, err
+	}
+	scoreTotal(score, &result.Total)
+	for i := range result.Groups {
+		scoreTotal(score, &result.Groups[i].Total)
+		for j := range result.Groups[i].Users {
+			scoreTotal(score, &result.Groups[i].Users[j])
+		}
+	}
+
+	return result, nil
+}
+
+// Проверить границы периода: обе даты обязательны, from <= to, from в пределах срока хранения,
+// период не длиннее maxPeriodDays. Будущие дни отчетов не содержат, поэтому to ограничивается сегодняшним днем
+func (s *djnService) parseRange(regionID uint, from, to string) (time.Time, time.Time, error) {
+	if from == "" || to == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf(
+			"%w: %v",
+			errs.ErrBadRequest,
+			"From and to parameters are required (format: YYYY-MM-DD)",
+		)
+	}
+
+	fromDate, err := time.ParseInLocation("2006-01-02", from, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf(
+			"%w: %v", errs.ErrBadRequest, "Invalid from date format. Use YYYY-MM-DD",
+		)
+	}
+	toDate, err := time.ParseInLocation("2006-01-02", to, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf(
+			"%w: %v", errs.ErrBadRequest, "Invalid to date format. Use YYYY-MM-DD",
+		)
+	}
+
+	if toDate.Before(fromDate) {
+		return time.Time{}, time.Time{}, fmt.Errorf(
+			"%w: %v", errs.ErrBadRequest, "From date must not be after to date",
+		)
+	}
+
+	now := time.Now()
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	if fromDate.After(today) {
+		return time.Time{}, time.Time{}, fmt.Errorf(
+			"%w: %v", errs.ErrBadRequest, "From date must not be in the future",
+		)
+	}
+	if toDate.After(today) {
+		toDate = today
+	}
+
+	if toDate.Sub(fromDate) >= maxPeriodDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf(
+			"%w: Period must not be longer than %d days",
+			errs.ErrBadRequest,
+			maxPeriodDays,
+		)
+	}
+
+	if !s.policy.IsRetained(regionID, fromDate, now) {
+		return time.Time{}, time.Time{}, fmt.Errorf(
+			"%w: From date is older than %d days, use /djin/archive for earlier months",
+			errs.ErrBadRequest,
+			s.policy.Days(regionID),
+		)
+	}
+
+	return fromDate, toDate, nil
+}
+
+// Границы группы, в которую попадает дата
+func periodBounds(date time.Time, group string) (key string, start time.Time, end time.Time) {
+	switch group {
+	case GroupWeek:
+		year, week := date.ISOWeek()
+		start = date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+		return fmt.Sprintf("%d-W%02d", year, week), start, start.AddDate(0, 0, 6)
+	case GroupMonth:
+		start = time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+		return start.Format("2006-01"), start, start.AddDate(0, 1, -1)
+	default:
+		return date.Format("2006-01-02"), date, date
+	}
+}
+
+// Разбить отчеты на группы. В ответ попадают все группы периода, в том числе пустые,
+// а границы крайних групп обрезаются по from и to
+func groupStats(
+	regionID uint,
+	stats []models.StatDaily,
+	from time.Time,
+	to time.Time,
+	group string,
+) []StatGroup {
+	var groups []StatGroup
+	index := make(map[string]int)
+
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		key, start, end := periodBounds(date, group)
+		if _, ok := index[key]; ok {
+			continue
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+
+		index[key] = len(groups)
+		groups = append(groups, StatGroup{
+			Key:  key,
+			From: start.Format("2006-01-02"),
+			To:   end.Format("2006-01-02"),
+		})
+	}
+
+	byGroup := make([][]models.StatDaily, len(groups))
+	for _, stat := range stats {
+		date, err := time.ParseInLocation("2006-01-02", stat.Date[:len("2006-01-02")], from.Location())
+		if err != nil {
+			continue
+		}
+		key, _, _ := periodBounds(date, group)
+		if i, ok := index[key]; ok {
+			byGroup[i] = append(byGroup[i], stat)
+		}
+	}
+
+	for i := range groups {
+		groups[i].Total = totalOf(regionID, byGroup[i])
+		groups[i].Users = userTotals(regionID, byGroup[i])
+	}
+
+	return groups
+}
+
+// Итог региона по отчетам
+func totalOf(regionID uint, stats []models.StatDaily) models.StatTotal {
+	return models.NewStatTotal("", regionID, len(stats), summa.Sum(stats...))
+}
+
+// Итоги по каждому пользователю, отсортированные по имени
+func userTotals(regionID uint, stats []models.StatDaily) []models.StatTotal {
+	byName := make(map[string][]models.StatDaily)
+	for _, stat := range stats {
+		byName[stat.Name] = append(byName[stat.Name], stat)
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	totals := make([]models.StatTotal, 0, len(names))
+	for _, name := range names {
+		totals = append(totals, models.NewStatTotal(name, regionID, len(byName[name]), summa.Sum(byName[name]...)))
+	}
+
+	return totals
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/repository"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"gorm.io/gorm"
+)
+
+type PlanTargetRequest struct {
+	Month    string  `json:"month"     binding:"required"` // YYYY-MM
+	RegionID uint    `json:"region_id" binding:"required"`
+	Name     string  `json:"name"` // пустое - цель региона
+	Code     string  `json:"code"      binding:"required"`
+	Amount   float64 `json:"amount"`
+	// Веса дней месяца, YYYY-MM-DD -> вес
+	Weights map[string]float64 `json:"weights"`
+}
+
+// План пользователя на день и цели, из которых он рассчитан
+type DailyPlan struct {
+	Date          string              `json:"date"`
+	Month         string              `json:"month"`
+	Targets       []models.PlanTarget `json:"targets"`
+	RegionTargets []models.PlanTarget `json:"region_targets"`
+	// Код продукта -> план на день
+	Plans map[string]float64 `json:"plans"`
+}
+
+type PlanService interface {
+	GetTargets(regionID uint, month string) ([]models.PlanTarget, error)
+	SaveTarget(req PlanTargetRequest, actor string) (*models.PlanTarget, error)
+	DeleteTarget(id uint) error
+	GetDailyPlan(regionID uint, name string, date string) (*DailyPlan, error)
+	// Заменить план отчета рассчитанным из месячных целей пользователя.
+	// Отличающийся план представителя сохраняется в ClaimedPlan
+	ApplyPlans(stat *models.StatDaily) error
+}
+
+type planService struct {
+	repo     repository.PlanRepo
+	products repository.ProductRepo
+	users    UserLookup
+	calendar WorkCalendar
+}
+
+func NewPlanService(
+	repo repository.PlanRepo,
+	products repository.ProductRepo,
+	users UserLookup,
+	calendar WorkCalendar,
+) PlanService {
+	return &planService{repo: repo, products: products, users: users, calendar: calendar}
+}
+
+func (s *planService) GetTargets(regionID uint, month string) ([]models.PlanTarget, error) {
+	start, err := parseMonth(month)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetPlanTargets(regionID, start.Format("2006-01-02"))
+}
+
+func (s *planService) SaveTarget(req PlanTargetRequest, actor string) (*models.PlanTarget, error) {
+	start, err := parseMonth(req.Month)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Amount < 0 {
+		return nil, fmt.Errorf("%w: amount must not be negative", errs.ErrBadRequest)
+	}
+
+	for day, weight := range req.Weights {
+		date, err := time.ParseInLocation("2006-01-02", day, time.Local)
+		if err != nil || date.Year() != start.Year() || date.Month() != start.Month() {
+			return nil, fmt.Errorf("%w: weight date %q is not a day of %s", errs.ErrBadRequest, day, req.Month)
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("%w: weight for %s must not be negative", errs.ErrBadRequest, day)
+		}
+	}
+
+	catalog, err := s.products.GetProducts(false)
+	if err != nil {
+		return nil, err
+	}
+	var product *models.Product
+	for i := range catalog {
+		if catalog[i].Code == req.Code {
+			product = &catalog[i]
+		}
+	}
+	if product == nil {
+		return nil, fmt.Errorf("%w: unknown product %q", errs.ErrBadRequest, req.Code)
+	}
+
+	if req.Name != "" {
+		user, err := s.users.GetUserByUsername(req.Name)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%w: user %q does not exist", errs.ErrBadRequest, req.Name)
+			}
+			return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+		}
+		if user.RegionID != req.RegionID {
+			return nil, fmt.Errorf(
+				"%w: user %q belongs to region %d",
+				errs.ErrBadRequest,
+				req.Name,
+				user.RegionID,
+			)
+		}
+	}
+
+	target := &models.PlanTarget{
+		Month:     start.Format("2006-01-02"),
+		RegionID:  req.RegionID,
+		Name:      req.Name,
+		ProductID: product.ID,
+		Code:      product.Code,
+		Amount:    req.Amount,
+		Weights:   req.Weights,
+		UpdatedBy: actor,
+		UpdatedAt: time.Now(),
+	}
+	if err := s.repo.SavePlanTarget(target); err != nil {
+		return nil, err
+	}
+
+	return target, nil
+}
+
+func (s *planService) DeleteTarget(id uint) error {
+	return s.repo.DeletePlanTarget(id)
+}
+
+func (s *planService) GetDailyPlan(regionID uint, name string, date string) (*DailyPlan, error) {
+	day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%w: Invalid date format. Use YYYY-MM-DD", errs.ErrBadRequest)
+	}
+	start := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.Local)
+
+	targets, err := s.repo.GetPlanTargets(regionID, start.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DailyPlan{
+		Date:          date,
+		Month:         start.Format("2006-01"),
+		Targets:       []models.PlanTarget{},
+		RegionTargets: []models.PlanTarget{},
+	}
+	for _, target := range targets {
+		switch target.Name {
+		case name:
+			result.Targets = append(result.Targets, target)
+		case "":
+			result.RegionTargets = append(result.RegionTargets, target)
+		}
+	}
+
+	result.Plans, err = s.dailyPlans(regionID, day, result.Targets)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *planService) ApplyPlans(stat *models.StatDaily) error {
+	day, err := time.ParseInLocation("2006-01-02", stat.Date, time.Local)
+	if err != nil {
+		return fmt.Errorf("%w: Invalid date format. Use YYYY-MM-DD", errs.ErrBadRequest)
+	}
+	month := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.Local).Format("2006-01-02")
+
+	targets, err := s.repo.GetUserPlanTargets(stat.RegionID, stat.Name, month)
+	if err != nil {
+		return err
+	}
+
+	plans, err := s.dailyPlans(stat.RegionID, day, targets)
+	if err != nil {
+		return err
+	}
+
+	for i := range stat.Products {
+		p := &stat.Products[i]
+
+		plan, ok := plans[p.Code]
+		if !ok {
+			p.PlanSource = models.PlanSourceClient
+			p.ClaimedPlan = nil
+			continue
+		}
+
+		claimed := p.Plan
+		p.Plan = plan
+		p.PlanSource = models.PlanSourceTarget
+		p.ClaimedPlan = nil
+		if claimed != 0 && math.Abs(claimed-plan) >= 0.005 {
+			p.ClaimedPlan = &claimed
+		}
+	}
+
+	stat.ComputeDifs()
+
+	return nil
+}
+
+// План на день по каждой цели: сумма цели пропорционально весу дня среди всех дней месяца
+func (s *planService) dailyPlans(
+	regionID uint,
+	day time.Time,
+	targets []models.PlanTarget,
+) (map[string]float64, error) {
+	plans := make(map[string]float64)
+	if len(targets) == 0 {
+		return plans, nil
+	}
+
+	start := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.Local)
+	end := start.AddDate(0, 1, -1)
+
+	workingDays, err := s.calendar.WorkingDays(regionID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, target := range targets {
+		plans[target.Code] = math.Round(planShare(target, workingDays, start, day, day)*100) / 100
+	}
+
+	return plans, nil
+}
+
+// Часть цели, приходящаяся на дни from..to месяца start. Вес дня задается явно
+// в цели, иначе 1 для рабочего дня и 0 для выходного
+func planShare(
+	target models.PlanTarget,
+	workingDays map[string]bool,
+	start time.Time,
+	from time.Time,
+	to time.Time,
+) float64 {
+	weight := func(d string) float64 {
+		if w, ok := target.Weights[d]; ok {
+			return w
+		}
+		if workingDays[d] {
+			return 1
+		}
+		return 0
+	}
+
+	end := start.AddDate(0, 1, -1)
+	total, share := 0.0, 0.0
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		w := weight(d.Format("2006-01-02"))
+		total += w
+		if !d.Before(from) && !d.After(to) {
+			share += w
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return target.Amount * share / total
+}
+
+// Первый день месяца из YYYY-MM
+func parseMonth(month string) (time.Time, error) {
+	start, err := time.ParseInLocation("2006-01", month, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: Invalid month format. Use YYYY-MM", errs.ErrBadRequest)
+	}
+	return start, nil
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Wladim1r/statcounter/internal/api/repository"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+)
+
+// Код продукта используется как префикс JSON-полей (<code>_plan, <code>_fact)
+var productCodeRe = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+type ProductRequest struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Position *int   `json:"position,omitempty"`
+	Active   *bool  `json:"active,omitempty"`
+}
+
+type ProductService interface {
+	GetProducts(activeOnly bool) ([]models.Product, error)
+	CreateProduct(req ProductRequest) (*models.Product, error)
+	UpdateProduct(id uint, req ProductRequest) (*models.Product, error)
+	DeleteProduct(id uint) error
+}
+
+type productService struct {
+	repo repository.ProductRepo
+}
+
+func NewProductService(repo repository.ProductRepo) ProductService {
+	return &productService{repo: repo}
+}
+
+func (s *productService) GetProducts(activeOnly bool) ([]models.Product, error) {
+	return s.repo.GetProducts(activeOnly)
+}
+
+func (s *productService) CreateProduct(req ProductRequest) (*models.Product, error) {
+	req.Code = strings.TrimSpace(req.Code)
+	req.Name = strings.TrimSpace(req.Name)
+
+	if !productCodeRe.MatchString(req.Code) {
+		return nil, fmt.Errorf(
+			"%w: %v",
+			errs.ErrBadRequest,
+			"Product code must contain only lowercase latin letters, digits and '_'",
+		)
+	}
+	if req.Name == "" {
+		return nil, fmt.Errorf("%w: %v", errs.ErrBadRequest, "Product name is required")
+	}
+
+	product := models.Product{
+		Code:   req.Code,
+		Name:   req.Name,
+		Active: true,
+	}
+	if req.Position != nil {
+		product.Position = *req.Position
+	}
+	if req.Active != nil {
+		product.Active = *req.Active
+	}
+
+	if err := s.repo.CreateProduct(&product); err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+func (s *productService) UpdateProduct(id uint, req ProductRequest) (*models.Product, error) {
+	// Код продукта неизменяем: по нему хранятся значения в уже отправленных отчетах
+	if req.Code != "" {
+		return nil, fmt.Errorf("%w: %v", errs.ErrBadRequest, "Product code cannot be changed")
+	}
+
+	updates := make(map[string]interface{})
+
+	if name := strings.TrimSpace(req.Name); name != "" {
+		updates["name"] = name
+	}
+	if req.Position != nil {
+		updates["position"] = *req.Position
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+
+	return s.repo.UpdateProduct(id, updates)
+}
+
+func (s *productService) DeleteProduct(id uint) error {
+	return s.repo.DeleteProduct(id)
+}
+
+// Привести продукты отчета к каталогу: неизвестные коды отклоняются, разница пересчитывается.
+// Проверяются только переданные продукты: пустые значения отключенного продукта отбрасываются.
+// При fillMissing отсутствующие активные продукты добавляются с нулевыми значениями
+func resolveProducts(catalog []models.Product, stat *models.StatDaily, fillMissing bool) error {
+	byCode := make(map[string]models.Product, len(catalog))
+	for _, product := range catalog {
+		byCode[product.Code] = product
+	}
+
+	submitted := stat.Products[:0]
+	for _, p := range stat.Products {
+		product, ok := byCode[p.Code]
+		if !ok {
+			return fmt.Errorf("%w: unknown product %s", errs.ErrBadRequest, p.Code)
+		}
+		if !product.Active {
+			// Форма, открытая до отключения продукта, может прислать его пустым
+			if p.Plan == 0 && p.Fact == 0 {
+				continue
+			}
+			return fmt.Errorf("%w: product %s is inactive", errs.ErrBadRequest, p.Code)
+		}
+		p.ProductID = product.ID
+		submitted = append(submitted, p)
+	}
+	stat.Products = submitted
+
+	for _, product := range catalog {
+		if fillMissing && product.Active && stat.Product(product.Code) == nil {
+			stat.Products = append(stat.Products, models.StatProduct{
+				ProductID: product.ID,
+				Code:      product.Code,
+			})
+		}
+	}
+
+	stat.ComputeDifs()
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/auth"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/models"
+	"gorm.io/gorm"
+)
+
+// Поиск пользователя по логину, реализуется auth.AuthService
+type UserLookup interface {
+	GetUserByUsername(username string) (*auth.User, error)
+}
+
+// Создать отчет от имени пользователя name за любую хранимую дату
+func (s *djnService) CreateStatFor(stat models.StatDaily, actor models.Actor) error {
+	if stat.Name == "" {
+		return fmt.Errorf("%w: name is required", errs.ErrBadRequest)
+	}
+
+	user, err := s.users.GetUserByUsername(stat.Name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: user %q does not exist", errs.ErrBadRequest, stat.Name)
+		}
+		return fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+	}
+
+	// Регион берется из пользователя, явно указанный должен с ним совпадать
+	if stat.RegionID == 0 {
+		stat.RegionID = user.RegionID
+	}
+	if stat.RegionID != user.RegionID {
+		return fmt.Errorf(
+			"%w: user %q belongs to region %d",
+			errs.ErrBadRequest,
+			stat.Name,
+			user.RegionID,
+		)
+	}
+
+	if stat.Date == "" {
+		return fmt.Errorf("%w: date is required (format: YYYY-MM-DD)", errs.ErrBadRequest)
+	}
+
+	return s.PostStat(stat, actor)
+}
+
+// Исправить значения отчета по ID, пользователь, регион и дата отчета не меняются
+func (s *djnService) UpdateStatByID(
+	id uint,
+	stat models.StatDaily,
+	actor models.Actor,
+	ifMatch string,
+) (*models.StatDaily, error) {
+	if ifMatch == "" {
+		return nil, fmt.Errorf("%w: If-Match header is required", errs.ErrPreconditionRequired)
+	}
+
+	old, err := s.repo.GetStatByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	stat.Name = old.Name
+	stat.RegionID = old.RegionID
+	stat.Date = old.Date
+	stat.UpdatedBy = actor.Username
+
+	if err := s.resolveProducts(&stat, false); err != nil {
+		return nil, err
+	}
+	if err := s.plans.ApplyPlans(&stat); err != nil {
+		return nil, err
+	}
+	if err := s.resolveMetrics(old.RegionID, &stat, false); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.PatchStatByID(id, &stat, actor, ifMatch); err != nil {
+		return nil, err
+	}
+
+	return &stat, nil
+}
+
+// Удаленный отчет остается в истории ревизий и может быть восстановлен
+func (s *djnService) DeleteStatByID(id uint, actor models.Actor) error {
+	_, err := s.repo.DeleteStat(id, actor)
+	return err
+}
+
+// Дата отчета не из будущего, еще хранится для региона и попадает в окно подачи для роли
+func (s *djnService) validateReportDate(regionID uint, date string, role string) error {
+	reportDate, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return fmt.Errorf("%w: Invalid date format. Use YYYY-MM-DD", errs.ErrBadRequest)
+	}
+
+	now := time.Now()
+	if reportDate.After(now) {
+		return fmt.Errorf("%w: date %s is in the future", errs.ErrBadRequest, date)
+	}
+	if !s.policy.IsRetained(regionID, reportDate, now) {
+		return fmt.Errorf(
+			"%w: date %s is older than %d days",
+			errs.ErrBadRequest,
+			date,
+			s.policy.Days(regionID),
+		)
+	}
+	if !s.backfill.Allowed(role, reportDate, now) {
+		return fmt.Errorf(
+			"%w: reports can be submitted only for the last %d days",
+			errs.ErrBadRequest,
+			s.backfill.UserDays(),
+		)
+	}
+
+	return nil
+}
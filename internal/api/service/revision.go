@@ -0,0 +1,104 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/repository"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/lib/retention"
+	"github.com/Wladim1r/statcounter/internal/models"
+)
+
+type RevisionService interface {
+	GetRevisions(filter repository.RevisionFilter) ([]models.StatRevision, error)
+	// Вернуть отчет к состоянию из ревизии, восстановление тоже попадает в историю
+	RestoreRevision(id uint, actor models.Actor) error
+}
+
+type revisionService struct {
+	revisions repository.RevisionRepo
+	repo      repository.DjnRepo
+	products  repository.ProductRepo
+	metrics   repository.MetricRepo
+	policy    *retention.Policy
+}
+
+func NewRevisionService(
+	revisions repository.RevisionRepo,
+	repo repository.DjnRepo,
+	products repository.ProductRepo,
+	metrics repository.MetricRepo,
+	policy *retention.Policy,
+) RevisionService {
+	return &revisionService{
+		revisions: revisions,
+		repo:      repo,
+		products:  products,
+		metrics:   metrics,
+		policy:    policy,
+	}
+}
+
+func (s *revisionService) GetRevisions(
+	filter repository.RevisionFilter,
+) ([]models.StatRevision, error) {
+	if filter.Date != "" {
+		if _, err := time.Parse("2006-01-02", filter.Date); err != nil {
+			return nil, fmt.Errorf("%w: Invalid date format. Use YYYY-MM-DD", errs.ErrBadRequest)
+		}
+	}
+
+	return s.revisions.GetRevisions(filter)
+}
+
+func (s *revisionService) RestoreRevision(id uint, actor models.Actor) error {
+	revision, err := s.revisions.GetRevisionByID(id)
+	if err != nil {
+		return err
+	}
+
+	// Отчеты за удаленные по сроку хранения дни уже в месячном архиве, повторно их не создаем
+	date, err := time.ParseInLocation("2006-01-02", revision.Date, time.Local)
+	if err != nil {
+		return fmt.Errorf("%w: invalid revision date %q", errs.ErrDBOperation, revision.Date)
+	}
+	if !s.policy.IsRetained(revision.RegionID, date, time.Now()) {
+		return fmt.Errorf(
+			"%w: date %s is older than %d days and already archived",
+			errs.ErrBadRequest,
+			revision.Date,
+			s.policy.Days(revision.RegionID),
+		)
+	}
+
+	stat, err := revision.Snapshot()
+	if err != nil {
+		return fmt.Errorf("%w: failed to decode revision %d: %v", errs.ErrDBOperation, id, err)
+	}
+
+	stat.ID = 0
+	stat.Name = revision.Name
+	stat.RegionID = revision.RegionID
+	stat.Date = revision.Date
+	stat.UpdatedBy = actor.Username
+	stat.CreatedAt, stat.UpdatedAt = time.Time{}, time.Time{}
+
+	catalog, err := s.products.GetProducts(false)
+	if err != nil {
+		return err
+	}
+	if err := resolveProducts(catalog, stat, false); err != nil {
+		return err
+	}
+
+	defs, err := s.metrics.GetMetricDefinitions(revision.RegionID, false)
+	if err != nil {
+		return err
+	}
+	if err := resolveMetrics(effectiveMetrics(defs), stat, false); err != nil {
+		return err
+	}
+
+	return s.repo.RestoreStat(revision.ID, stat, actor)
+}
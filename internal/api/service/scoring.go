@@ -0,0 +1,161 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/repository"
+	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/lib/scoring"
+	"github.com/Wladim1r/statcounter/internal/models"
+)
+
+type ScoringFormulaRequest struct {
+	RegionID uint               `json:"region_id"` // 0 - для регионов без своей формулы
+	Name     string             `json:"name"`
+	Terms    []models.ScoreTerm `json:"terms"     binding:"required"`
+}
+
+// Оценка отчета или суммы отчетов региона, nil - оценку посчитать нельзя
+type Scorer func(stat models.StatDaily) *float64
+
+type ScoringService interface {
+	GetFormulas() ([]models.ScoringFormula, error)
+	SaveFormula(req ScoringFormulaRequest, actor string) (*models.ScoringFormula, error)
+	DeleteFormula(id uint) error
+	// Оценка по формуле региона, иначе по общей формуле, иначе средний процент плана
+	Scorer(regionID uint) (Scorer, error)
+}
+
+type scoringService struct {
+	repo     repository.ScoringRepo
+	products repository.ProductRepo
+	metrics  repository.MetricRepo
+	regions  RegionLister
+}
+
+func NewScoringService(
+	repo repository.ScoringRepo,
+	products repository.ProductRepo,
+	metrics repository.MetricRepo,
+	regions RegionLister,
+) ScoringService {
+	return &scoringService{repo: repo, products: products, metrics: metrics, regions: regions}
+}
+
+func (s *scoringService) GetFormulas() ([]models.ScoringFormula, error) {
+	return s.repo.GetFormulas()
+}
+
+func (s *scoringService) SaveFormula(
+	req ScoringFormulaRequest,
+	actor string,
+) (*models.ScoringFormula, error) {
+	if req.RegionID != 0 {
+		regions, err := s.regions.GetRegions()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errs.ErrDBOperation, err)
+		}
+		found := false
+		for _, region := range regions {
+			found = found || region.ID == req.RegionID
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: region %d not found", errs.ErrBadRequest, req.RegionID)
+		}
+	}
+
+	catalog, err := s.products.GetProducts(false)
+	if err != nil {
+		return nil, err
+	}
+	products := make(map[string]bool, len(catalog))
+	for _, p := range catalog {
+		products[p.Code] = true
+	}
+
+	// Общая формула может ссылаться на счетчик любого региона
+	var defs []models.MetricDefinition
+	if req.RegionID == 0 {
+		defs, err = s.metrics.GetAllMetricDefinitions()
+	} else {
+		defs, err = s.metrics.GetMetricDefinitions(req.RegionID, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	metrics := make(map[string]bool, len(defs))
+	for _, def := range defs {
+		metrics[def.Code] = true
+	}
+
+	if err := scoring.Validate(req.Terms, products, metrics); err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrBadRequest, err)
+	}
+
+	formula := &models.ScoringFormula{
+		RegionID:  req.RegionID,
+		Name:      req.Name,
+		Terms:     req.Terms,
+		UpdatedBy: actor,
+		UpdatedAt: time.Now(),
+	}
+	if err := s.repo.SaveFormula(formula); err != nil {
+		return nil, err
+	}
+
+	return formula, nil
+}
+
+func (s *scoringService) DeleteFormula(id uint) error {
+	return s.repo.DeleteFormula(id)
+}
+
+func (s *scoringService) Scorer(regionID uint) (Scorer, error) {
+	formulas, err := s.repo.GetRegionFormulas(regionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(formulas) == 0 {
+		return defaultScore, nil
+	}
+
+	// Формулы упорядочены по региону, поэтому формула региона идет последней
+	terms := formulas[len(formulas)-1].Terms
+	return func(stat models.StatDaily) *float64 {
+		score := scoring.Evaluate(terms, stat)
+		return &score
+	}, nil
+}
+
+// Оценка без настроенной формулы: средний процент выполнения плана по продуктам с ненулевым планом
+func defaultScore(stat models.StatDaily) *float64 {
+	var sum float64
+	var count int
+
+	for _, p := range stat.Products {
+		if p.Plan == 0 {
+			continue
+		}
+		sum += p.Fact / p.Plan * 100
+		count++
+	}
+
+	if count == 0 {
+		return nil
+	}
+	score := round2(sum / float64(count))
+	return &score
+}
+
+// Заполнить оценки отчетов
+func scoreStats(score Scorer, stats []models.StatDaily) {
+	for i := range stats {
+		stats[i].Score = score(stats[i])
+	}
+}
+
+// Заполнить оценку агрегата
+func scoreTotal(score Scorer, total *models.StatTotal) {
+	total.Score = score(total.Stat())
+}
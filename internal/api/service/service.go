@@ -6,25 +6,89 @@ import (
 	"time"
 
 	"github.com/Wladim1r/statcounter/internal/api/repository"
+	"github.com/Wladim1r/statcounter/internal/lib/backfill"
 	"github.com/Wladim1r/statcounter/internal/lib/errs"
+	"github.com/Wladim1r/statcounter/internal/lib/export"
+	"github.com/Wladim1r/statcounter/internal/lib/retention"
 	"github.com/Wladim1r/statcounter/internal/models"
 )
 
 type DjnService interface {
-	PostStat(stat models.StatDaily) error
-	PatchStat(regionID uint, stat models.StatDaily) error
+	// Подать отчет за stat.Date (по умолчанию сегодня) с учетом окна подачи для роли actor
+	PostStat(stat models.StatDaily, actor models.Actor) error
+	// Изменить отчет версии ifMatch (ETag), возвращает обновленный отчет
+	PatchStat(
+		regionID uint,
+		stat models.StatDaily,
+		actor models.Actor,
+		ifMatch string,
+	) (*models.StatDaily, error)
 	GetStatByRegion(regionID uint) ([]models.StatDaily, error)
 	GetStatByRegionAndUser(regionID uint, username string) ([]models.StatDaily, error)
 	GetStatsByMonth(regionID uint, date string) ([]models.StatDaily, error)
 	GetStatsByMonthAndUser(regionID uint, username string, date string) ([]models.StatDaily, error)
+	// Отчеты за период from..to с группировкой по дням, ISO-неделям или месяцам
+	GetStatsByRange(regionID uint, username string, from string, to string, group string) (*RangeStats, error)
+	// Агрегат и количество отчетов региона за дату
+	GetRegionTotals(regionID uint, date string) (models.StatDaily, int, error)
+	RebuildStats() error
+	// Сверить агрегаты с БД, возвращает число исправленных расхождений
+	ReconcileStats() (int, error)
+	GetRetention(regionID uint) retention.Info
+	GetBackfill(role string) backfill.Info
+	// Административные операции с отчетом любого пользователя
+	CreateStatFor(stat models.StatDaily, actor models.Actor) error
+	UpdateStatByID(
+		id uint,
+		stat models.StatDaily,
+		actor models.Actor,
+		ifMatch string,
+	) (*models.StatDaily, error)
+	DeleteStatByID(id uint, actor models.Actor) error
+	// Таблица отчетов региона или пользователя за дату или период и имя файла без расширения
+	ExportStats(query ExportQuery) (*export.Table, string, error)
+	// Импорт отчетов региона из CSV или XLSX. Все строки сохраняются в одной транзакции
+	// и только если ни в одной нет ошибок, при dryRun только проверяются
+	ImportStats(
+		regionID uint,
+		filename string,
+		data []byte,
+		actor models.Actor,
+		dryRun bool,
+	) (*ImportResult, error)
 }
 
 type djnService struct {
-	repo repository.DjnRepo
+	repo     repository.DjnRepo
+	products repository.ProductRepo
+	metrics  repository.MetricRepo
+	policy   *retention.Policy
+	backfill *backfill.Policy
+	users    UserLookup
+	plans    PlanService
+	scores   ScoringService
 }
 
-func NewDjnService(repo repository.DjnRepo) DjnService {
-	return &djnService{repo: repo}
+func NewDjnService(
+	repo repository.DjnRepo,
+	products repository.ProductRepo,
+	metrics repository.MetricRepo,
+	policy *retention.Policy,
+	backfill *backfill.Policy,
+	users UserLookup,
+	plans PlanService,
+	scores ScoringService,
+) DjnService {
+	return &djnService{
+		repo:     repo,
+		products: products,
+		metrics:  metrics,
+		policy:   policy,
+		backfill: backfill,
+		users:    users,
+		plans:    plans,
+		scores:   scores,
+	}
 }
 
 func (s *djnService) GetStatsByMonth(regionID uint, date string) ([]models.StatDaily, error) {
@@ -37,41 +101,96 @@ func (s *djnService) GetStatsByMonth(regionID uint, date string) ([]models.StatD
 	}
 
 	// Валидируем формат даты
-	if _, err := time.Parse("2006-01-02", date); err != nil {
+	requestedDate, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
 		return nil, fmt.Errorf("%w: %v", errs.ErrBadRequest, "Invalid date format. Use YYYY-MM-DD")
 	}
 
-	// Проверяем, что дата не старше 30 дней
-	requestedDate, _ := time.Parse("2006-01-02", date)
-	thirtyDaysAgo := time.Now().AddDate(0, 0, -30)
-	if requestedDate.Before(thirtyDaysAgo) {
-		return nil, fmt.Errorf("%w: %v", errs.ErrBadRequest, "Requested date is older than 30 days")
+	// Проверяем, что дата еще хранится для региона
+	if !s.policy.IsRetained(regionID, requestedDate, time.Now()) {
+		return nil, fmt.Errorf(
+			"%w: Requested date is older than %d days",
+			errs.ErrBadRequest,
+			s.policy.Days(regionID),
+		)
 	}
 
-	return s.repo.GetStatsByMonth(regionID, date)
+	stats, err := s.repo.GetStatsByMonth(regionID, date)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, s.scoreStats(regionID, stats)
 }
 
-func (s *djnService) PatchStat(regionID uint, stat models.StatDaily) error {
+func (s *djnService) PatchStat(
+	regionID uint,
+	stat models.StatDaily,
+	actor models.Actor,
+	ifMatch string,
+) (*models.StatDaily, error) {
+	if ifMatch == "" {
+		return nil, fmt.Errorf("%w: If-Match header is required", errs.ErrPreconditionRequired)
+	}
+
+	// Без явной даты редактируется отчет за сегодня
+	if stat.Date == "" {
+		stat.Date = time.Now().Format("2006-01-02")
+	}
+
+	if err := s.validateReportDate(regionID, stat.Date, actor.Role); err != nil {
+		return nil, err
+	}
+	stat.RegionID = regionID
+	stat.UpdatedBy = actor.Username
+
+	if err := s.resolveProducts(&stat, false); err != nil {
+		return nil, err
+	}
+	if err := s.plans.ApplyPlans(&stat); err != nil {
+		return nil, err
+	}
+	if err := s.resolveMetrics(regionID, &stat, false); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.PatchStat(regionID, &stat, actor, ifMatch); err != nil {
+		return nil, err
+	}
 
-	seedDif := stat.SeedFact - stat.SeedPlan
-	pumpkinDif := stat.PumpkinFact - stat.PumpkinPlan
-	peanutDif := stat.PeanutFact - stat.PeanutPlan
+	return &stat, nil
+}
 
-	stat.SeedDif = seedDif
-	stat.PumpkinDif = pumpkinDif
-	stat.PeanutDif = peanutDif
+func (s *djnService) PostStat(stat models.StatDaily, actor models.Actor) error {
+	if err := s.prepareStat(&stat, actor); err != nil {
+		return err
+	}
 
-	return s.repo.PatchStat(regionID, &stat)
+	return s.repo.PostStat(&stat, actor)
 }
 
-func (s *djnService) PostStat(stat models.StatDaily) error {
-	// Проверяем, существует ли уже запись за сегодня для данного пользователя
-	today := time.Now().Format("2006-01-02")
-	existingStats, err := s.repo.GetStatsByMonthAndUser(stat.RegionID, stat.Name, today)
+// Проверить новый отчет и привести его к каталогу: дата, дубликат, продукты, планы, счетчики
+func (s *djnService) prepareStat(stat *models.StatDaily, actor models.Actor) error {
+	if stat.Date == "" {
+		stat.Date = time.Now().Format("2006-01-02")
+	}
+
+	if err := s.validateReportDate(stat.RegionID, stat.Date, actor.Role); err != nil {
+		return err
+	}
+
+	// Время создания и изменения выставляет БД
+	stat.ID = 0
+	stat.CreatedBy = actor.Username
+	stat.UpdatedBy = ""
+	stat.CreatedAt, stat.UpdatedAt = time.Time{}, time.Time{}
+
+	// Проверяем, существует ли уже запись за эту дату для данного пользователя
+	existingStats, err := s.repo.GetStatsByMonthAndUser(stat.RegionID, stat.Name, stat.Date)
 
 	// Если нашли записи (и это не ошибка "не найдено"), значит дублирование
 	if err == nil && len(existingStats) > 0 {
-		return fmt.Errorf("%w: You have already submitted data for today", errs.ErrUniqueName)
+		return fmt.Errorf("%w: You have already submitted data for %s", errs.ErrUniqueName, stat.Date)
 	}
 
 	// Если это ошибка не "не найдено", то что-то не так с БД
@@ -79,27 +198,39 @@ func (s *djnService) PostStat(stat models.StatDaily) error {
 		return fmt.Errorf("%w: failed to check existing records: %v", errs.ErrDBOperation, err)
 	}
 
-	// Вычисляем разности
-	seedDif := stat.SeedFact - stat.SeedPlan
-	pumpkinDif := stat.PumpkinFact - stat.PumpkinPlan
-	peanutDif := stat.PeanutFact - stat.PeanutPlan
+	// Сопоставляем продукты с каталогом и вычисляем разности
+	if err := s.resolveProducts(stat, true); err != nil {
+		return err
+	}
 
-	stat.SeedDif = seedDif
-	stat.PumpkinDif = pumpkinDif
-	stat.PeanutDif = peanutDif
+	// План по продуктам с месячной целью рассчитывается сервером
+	if err := s.plans.ApplyPlans(stat); err != nil {
+		return err
+	}
 
-	return s.repo.PostStat(&stat)
+	// Проверяем счетчики по определениям региона
+	return s.resolveMetrics(stat.RegionID, stat, true)
 }
 
 func (s *djnService) GetStatByRegion(regionID uint) ([]models.StatDaily, error) {
-	return s.repo.GetStatsByRegion(regionID)
+	stats, err := s.repo.GetStatsByRegion(regionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, s.scoreStats(regionID, stats)
 }
 
 func (s *djnService) GetStatByRegionAndUser(
 	regionID uint,
 	username string,
 ) ([]models.StatDaily, error) {
-	return s.repo.GetStatsByRegionAndUser(regionID, username)
+	stats, err := s.repo.GetStatsByRegionAndUser(regionID, username)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, s.scoreStats(regionID, stats)
 }
 
 func (s *djnService) GetStatsByMonthAndUser(
@@ -107,5 +238,59 @@ func (s *djnService) GetStatsByMonthAndUser(
 	username string,
 	date string,
 ) ([]models.StatDaily, error) {
-	return s.repo.GetStatsByMonthAndUser(regionID, username, date)
+	stats, err := s.repo.GetStatsByMonthAndUser(regionID, username, date)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, s.scoreStats(regionID, stats)
+}
+
+// Заполнить оценки отчетов по формуле региона
+func (s *djnService) scoreStats(regionID uint, stats []models.StatDaily) error {
+	score, err := s.scores.Scorer(regionID)
+	if err != nil {
+		return err
+	}
+
+	scoreStats(score, stats)
+	return nil
+}
+
+func (s *djnService) GetRegionTotals(regionID uint, date string) (models.StatDaily, int, error) {
+	return s.repo.GetRegionTotals(regionID, date)
+}
+
+func (s *djnService) RebuildStats() error {
+	return s.repo.RebuildStats()
+}
+
+func (s *djnService) ReconcileStats() (int, error) {
+	return s.repo.ReconcileStats()
+}
+
+func (s *djnService) resolveProducts(stat *models.StatDaily, fillMissing bool) error {
+	catalog, err := s.products.GetProducts(false)
+	if err != nil {
+		return err
+	}
+
+	return resolveProducts(catalog, stat, fillMissing)
+}
+
+func (s *djnService) resolveMetrics(regionID uint, stat *models.StatDaily, fillMissing bool) error {
+	defs, err := s.metrics.GetMetricDefinitions(regionID, false)
+	if err != nil {
+		return err
+	}
+
+	return resolveMetrics(effectiveMetrics(defs), stat, fillMissing)
+}
+
+func (s *djnService) GetRetention(regionID uint) retention.Info {
+	return s.policy.Info(regionID, time.Now())
+}
+
+func (s *djnService) GetBackfill(role string) backfill.Info {
+	return s.backfill.Info(role, time.Now())
 }
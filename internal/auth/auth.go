@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/mail"
+	"strings"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
@@ -31,6 +33,27 @@ type User struct {
 	Role     string `json:"role"             gorm:"not null;default:'user'"`
 	RegionID uint   `json:"region_id"        gorm:"not null"`
 	Region   Region `json:"region,omitempty" gorm:"foreignKey:RegionID"`
+	// Неактивные пользователи (уволенные, в отпуске) не ожидаются в списке сдавших отчет
+	Active bool `json:"active" gorm:"not null;default:true"`
+	Contacts
+}
+
+// Контакты для уведомлений: сводки, напоминания
+type Contacts struct {
+	Email          string `json:"email,omitempty"            gorm:"not null;default:''"`
+	TelegramChatID string `json:"telegram_chat_id,omitempty" gorm:"not null;default:''"`
+}
+
+func (c *Contacts) validate() error {
+	c.Email = strings.TrimSpace(c.Email)
+	c.TelegramChatID = strings.TrimSpace(c.TelegramChatID)
+
+	if c.Email != "" {
+		if _, err := mail.ParseAddress(c.Email); err != nil {
+			return fmt.Errorf("invalid email %q", c.Email)
+		}
+	}
+	return nil
 }
 
 type LoginRequest struct {
@@ -54,6 +77,7 @@ type CreateUserRequest struct {
 	Password string `json:"password"  binding:"required,min=3"`
 	Role     string `json:"role"      binding:"required,oneof=user admin"`
 	RegionID uint   `json:"region_id" binding:"required"`
+	Contacts
 }
 
 type UpdateUserRequest struct {
@@ -61,6 +85,10 @@ type UpdateUserRequest struct {
 	Password string `json:"password,omitempty"`
 	Role     string `json:"role,omitempty"      binding:"omitempty,oneof=user admin"`
 	RegionID uint   `json:"region_id,omitempty"`
+	// nil - не менять, пустая строка - удалить контакт
+	Email          *string `json:"email,omitempty"`
+	TelegramChatID *string `json:"telegram_chat_id,omitempty"`
+	Active         *bool   `json:"active,omitempty"`
 }
 
 type UserResponse struct {
@@ -69,6 +97,8 @@ type UserResponse struct {
 	Role     string `json:"role"`
 	RegionID uint   `json:"region_id"`
 	Region   Region `json:"region"`
+	Active   bool   `json:"active"`
+	Contacts
 }
 
 // Сервис авторизации
@@ -104,7 +134,11 @@ func (s *AuthService) Authenticate(username, password string) (*User, error) {
 	return &user, nil
 }
 
-func (s *AuthService) CreateUser(username, password, role string, regionID uint) (*User, error) {
+func (s *AuthService) CreateUser(
+	username, password, role string,
+	regionID uint,
+	contacts Contacts,
+) (*User, error) {
 	// Проверяем существование региона
 	var region Region
 	if err := s.db.First(&region, regionID).Error; err != nil {
@@ -121,6 +155,11 @@ func (s *AuthService) CreateUser(username, password, role string, regionID uint)
 		Password: hashedPassword,
 		Role:     role,
 		RegionID: regionID,
+		Active:   true,
+		Contacts: contacts,
+	}
+	if err := user.Contacts.validate(); err != nil {
+		return nil, err
 	}
 
 	err = s.db.Create(&user).Error
@@ -161,6 +200,22 @@ func (s *AuthService) UpdateUser(userID uint, req UpdateUserRequest) (*User, err
 		updates["region_id"] = req.RegionID
 	}
 
+	if req.Email != nil {
+		contacts := Contacts{Email: *req.Email}
+		if err := contacts.validate(); err != nil {
+			return nil, err
+		}
+		updates["email"] = contacts.Email
+	}
+
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+
+	if req.TelegramChatID != nil {
+		updates["telegram_chat_id"] = strings.TrimSpace(*req.TelegramChatID)
+	}
+
 	if err := s.db.Model(&user).Updates(updates).Error; err != nil {
 		return nil, err
 	}
@@ -189,6 +244,12 @@ func (s *AuthService) GetUserByID(id uint) (*User, error) {
 	return &user, err
 }
 
+func (s *AuthService) GetUserByUsername(username string) (*User, error) {
+	var user User
+	err := s.db.Preload("Region").Where("username = ?", username).First(&user).Error
+	return &user, err
+}
+
 func (s *AuthService) GetRegions() ([]Region, error) {
 	var regions []Region
 	err := s.db.Find(&regions).Error
@@ -330,7 +391,13 @@ func (ac *AuthController) CreateUser(c *gin.Context) {
 		return
 	}
 
-	user, err := ac.authService.CreateUser(req.Username, req.Password, req.Role, req.RegionID)
+	user, err := ac.authService.CreateUser(
+		req.Username,
+		req.Password,
+		req.Role,
+		req.RegionID,
+		req.Contacts,
+	)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
@@ -352,6 +419,8 @@ func (ac *AuthController) CreateUser(c *gin.Context) {
 			Role:     fullUser.Role,
 			RegionID: fullUser.RegionID,
 			Region:   fullUser.Region,
+			Active:   fullUser.Active,
+			Contacts: fullUser.Contacts,
 		},
 	})
 }
@@ -398,6 +467,8 @@ func (ac *AuthController) UpdateUser(c *gin.Context) {
 			Role:     user.Role,
 			RegionID: user.RegionID,
 			Region:   user.Region,
+			Active:   user.Active,
+			Contacts: user.Contacts,
 		},
 	})
 }
@@ -448,6 +519,8 @@ func (ac *AuthController) GetAllUsers(c *gin.Context) {
 			Role:     user.Role,
 			RegionID: user.RegionID,
 			Region:   user.Region,
+			Active:   user.Active,
+			Contacts: user.Contacts,
 		})
 	}
 
@@ -663,7 +736,7 @@ func InitializeRegions(db *gorm.DB) error {
 		if err := db.Where("username = ?", u.Username).First(&existing).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
 				regionID := regionMap[u.RegionName]
-				_, err := authService.CreateUser(u.Username, u.Password, u.Role, regionID)
+				_, err := authService.CreateUser(u.Username, u.Password, u.Role, regionID, Contacts{})
 				if err != nil {
 					return err
 				}
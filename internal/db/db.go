@@ -37,9 +37,46 @@ func InitDB() (*gorm.DB, error) {
 		return nil, fmt.Errorf("could not connect to DB %w", err)
 	}
 
-	if err := db.AutoMigrate(&auth.Region{}, &auth.User{}, &models.StatDaily{}); err != nil {
+	if err := db.AutoMigrate(
+		&auth.Region{},
+		&auth.User{},
+		&models.StatDaily{},
+		&models.Product{},
+		&models.StatProduct{},
+		&models.MetricDefinition{},
+		&models.StatMetric{},
+		&models.MonthlySummary{},
+		&models.MonthlySummaryProduct{},
+		&models.MonthlySummaryMetric{},
+		&models.StatRevision{},
+		&models.StatEvent{},
+		&models.PlanTarget{},
+		&models.WorkWeek{},
+		&models.CalendarDay{},
+		&models.ScoringFormula{},
+		&models.BonusRule{},
+		&models.BonusRun{},
+		&models.BonusPayout{},
+		&models.NotificationLog{},
+	); err != nil {
 		return nil, fmt.Errorf("error when creation DB %w", err)
 	}
 
+	if err := migrateLegacyProducts(db); err != nil {
+		return nil, fmt.Errorf("error when migrating product columns %w", err)
+	}
+
+	if err := seedProducts(db); err != nil {
+		return nil, fmt.Errorf("error when seeding products %w", err)
+	}
+
+	if err := migrateLegacyMetrics(db); err != nil {
+		return nil, fmt.Errorf("error when migrating metric columns %w", err)
+	}
+
+	if err := seedMetrics(db); err != nil {
+		return nil, fmt.Errorf("error when seeding metrics %w", err)
+	}
+
 	return db, nil
 }
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/Wladim1r/statcounter/internal/models"
+	"gorm.io/gorm"
+)
+
+// Продукты, которые раньше были колонками stat_dailies
+var defaultProducts = []models.Product{
+	{Code: "seed", Name: "Семечка", Position: 1, Active: true},
+	{Code: "pumpkin", Name: "Тыква", Position: 2, Active: true},
+	{Code: "peanut", Name: "Арахис", Position: 3, Active: true},
+}
+
+// Счетчики, которые раньше были колонками stat_dailies (колонка -> определение)
+var defaultMetrics = []struct {
+	Column string
+	Metric models.MetricDefinition
+}{
+	{"akb1", models.MetricDefinition{Code: "akb1", Name: "АКБ1", Position: 1}},
+	{"akb2", models.MetricDefinition{Code: "akb2", Name: "АКБ2", Position: 2}},
+	{"new_tt", models.MetricDefinition{Code: "newtt", Name: "Новые ТТ", Position: 3}},
+	{"mix", models.MetricDefinition{Code: "mix", Name: "Микс", Position: 4}},
+	{"np_one", models.MetricDefinition{Code: "npone", Name: "N+1", Position: 5}},
+	{"set_shel", models.MetricDefinition{Code: "set_shelving", Name: "Установка стеллажа", Position: 6}},
+	{"dmp", models.MetricDefinition{Code: "dmp", Name: "ДМП", Position: 7}},
+	{"top_five", models.MetricDefinition{Code: "top_five", Name: "Топ 5", Position: 8}},
+	{"news", models.MetricDefinition{Code: "news", Name: "Новинки", Position: 9}},
+}
+
+// Заполнить каталог продуктов значениями по умолчанию, если он пуст
+func seedProducts(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.Product{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	products := append([]models.Product(nil), defaultProducts...)
+	return db.Create(&products).Error
+}
+
+// Перенести значения из старых колонок seed_*, pumpkin_*, peanut_* в stat_products
+func migrateLegacyProducts(db *gorm.DB) error {
+	if !db.Migrator().HasColumn("stat_dailies", "seed_plan") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, p := range defaultProducts {
+			var product models.Product
+			if err := tx.Where(models.Product{Code: p.Code}).
+				Attrs(models.Product{Name: p.Name, Position: p.Position, Active: true}).
+				FirstOrCreate(&product).Error; err != nil {
+				return err
+			}
+
+			query := fmt.Sprintf(`
+				INSERT INTO stat_products (stat_daily_id, product_id, code, plan, fact, dif)
+				SELECT id, ?, ?, COALESCE(%[1]s_plan, 0), COALESCE(%[1]s_fact, 0),
+					COALESCE(%[1]s_fact, 0) - COALESCE(%[1]s_plan, 0)
+				FROM stat_dailies
+				ON CONFLICT (stat_daily_id, product_id) DO NOTHING`, p.Code)
+			if err := tx.Exec(query, product.ID, product.Code).Error; err != nil {
+				return err
+			}
+
+			for _, suffix := range []string{"_plan", "_fact", "_dif"} {
+				if err := tx.Migrator().DropColumn("stat_dailies", p.Code+suffix); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// Заполнить общие (для всех регионов) счетчики значениями по умолчанию, если определений нет
+func seedMetrics(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.MetricDefinition{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	for _, m := range defaultMetrics {
+		def := defaultMetric(m.Metric)
+		if err := db.Create(&def).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Перенести значения из старых колонок akb1, akb2, new_tt, ... в stat_metrics
+func migrateLegacyMetrics(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, m := range defaultMetrics {
+			if !tx.Migrator().HasColumn("stat_dailies", m.Column) {
+				continue
+			}
+
+			def := defaultMetric(m.Metric)
+			if err := tx.Where("region_id = ? AND code = ?", 0, def.Code).
+				Attrs(def).
+				FirstOrCreate(&def).Error; err != nil {
+				return err
+			}
+
+			query := fmt.Sprintf(`
+				INSERT INTO stat_metrics (stat_daily_id, code, value)
+				SELECT id, ?, COALESCE(%s, 0)
+				FROM stat_dailies
+				ON CONFLICT (stat_daily_id, code) DO NOTHING`, m.Column)
+			if err := tx.Exec(query, def.Code).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Migrator().DropColumn("stat_dailies", m.Column); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func defaultMetric(def models.MetricDefinition) models.MetricDefinition {
+	def.Type = models.MetricTypeCounter
+	def.Active = true
+	return def
+}
@@ -0,0 +1,76 @@
+package backfill
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/auth"
+)
+
+// Сколько прошедших дней пользователь может дозаполнить по умолчанию, если BACKFILL_DAYS не задан
+const defaultUserDays = 1
+
+// Политика подачи отчетов задним числом: пользователи могут дозаполнить последние userDays дней,
+// администраторы - любой день, который еще хранится
+type Policy struct {
+	userDays int
+}
+
+// Окно подачи отчетов для роли
+type Info struct {
+	// -1 для администраторов: ограничение только сроком хранения
+	Days         int    `json:"days"`
+	EarliestDate string `json:"earliest_date,omitempty"`
+}
+
+// Загрузить политику из переменной окружения BACKFILL_DAYS (0 - только сегодня)
+func FromEnv() (*Policy, error) {
+	return Parse(os.Getenv("BACKFILL_DAYS"))
+}
+
+func Parse(value string) (*Policy, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return &Policy{userDays: defaultUserDays}, nil
+	}
+
+	days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+	if err != nil || days < 0 {
+		return nil, fmt.Errorf("invalid BACKFILL_DAYS %q: expected non-negative number of days", value)
+	}
+
+	return &Policy{userDays: days}, nil
+}
+
+func (p *Policy) UserDays() int {
+	return p.userDays
+}
+
+// Самая ранняя дата, за которую пользователь может подать отчет
+func (p *Policy) Earliest(now time.Time) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return today.AddDate(0, 0, -p.userDays)
+}
+
+// Может ли роль подать или изменить отчет за date. Срок хранения проверяется отдельно
+func (p *Policy) Allowed(role string, date time.Time, now time.Time) bool {
+	if role == auth.RoleAdmin {
+		return true
+	}
+
+	return !date.Before(p.Earliest(now))
+}
+
+func (p *Policy) Info(role string, now time.Time) Info {
+	if role == auth.RoleAdmin {
+		return Info{Days: -1}
+	}
+
+	return Info{
+		Days:         p.userDays,
+		EarliestDate: p.Earliest(now).Format("2006-01-02"),
+	}
+}
@@ -7,4 +7,11 @@ var (
 	ErrNotFound    = errors.New("record not found")
 	ErrUniqueName  = errors.New("duplicate name")
 	ErrBadRequest  = errors.New("bad request")
+	ErrConflict    = errors.New("conflict")
+	// Изменение записи без заголовка If-Match
+	ErrPreconditionRequired = errors.New("precondition required")
+	// Версия записи не совпала с If-Match клиента
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// Функция не настроена на сервере (например, нет шрифта для PDF)
+	ErrUnavailable = errors.New("service unavailable")
 )
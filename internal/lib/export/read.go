@@ -0,0 +1,244 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Наибольший размер распакованной части XLSX-файла
+	maxXLSXPartSize = 32 << 20
+	// Пределы листа Excel: строк и столбцов (XFD)
+	maxXLSXRows    = 1 << 20
+	maxXLSXColumns = 16384
+	// Наибольшее число ячеек листа вместе с пропущенными пустыми,
+	// иначе редкие ячейки в дальних столбцах раздувают память при выравнивании строк
+	maxXLSXCells = 1 << 22
+)
+
+// Прочитать строки таблицы из CSV или XLSX (по расширению или сигнатуре ZIP).
+// Для XLSX читается первый лист, значения возвращаются как в ячейках
+func Read(filename string, data []byte) ([][]string, error) {
+	if strings.EqualFold(filepath.Ext(filename), ".xlsx") || bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return ReadXLSX(data)
+	}
+	return ReadCSV(data)
+}
+
+// CSV с разделителем запятая или точка с запятой (определяется по первой строке)
+func ReadCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	if firstLine, _, _ := bytes.Cut(data, []byte("\n")); bytes.Count(firstLine, []byte(";")) >
+		bytes.Count(firstLine, []byte(",")) {
+		r.Comma = ';'
+	}
+
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	return rows, nil
+}
+
+type xlsxRel struct {
+	ID     string `xml:"Id,attr"`
+	Target string `xml:"Target,attr"`
+}
+
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+type xlsxCell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Value  string   `xml:"v"`
+	Inline xlsxText `xml:"is"`
+}
+
+func ReadXLSX(data []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid XLSX: %w", err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			Items []xlsxText `xml:"si"`
+		}
+		if err := decodePart(f, &sst); err != nil {
+			return nil, err
+		}
+		for _, item := range sst.Items {
+			shared = append(shared, item.String())
+		}
+	}
+
+	f, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("invalid XLSX: sheet %s not found", sheetPath)
+	}
+	var sheet struct {
+		Rows []struct {
+			Cells []xlsxCell `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := decodePart(f, &sheet); err != nil {
+		return nil, err
+	}
+
+	if len(sheet.Rows) > maxXLSXRows {
+		return nil, fmt.Errorf("invalid XLSX: more than %d rows", maxXLSXRows)
+	}
+
+	cells := 0
+	rows := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		var values []string
+		for _, cell := range row.Cells {
+			// Пустые ячейки в XLSX пропускаются, позиция берется из ссылки ячейки
+			column := len(values)
+			if cell.Ref != "" {
+				column, err = columnIndex(cell.Ref)
+				if err != nil {
+					return nil, err
+				}
+			}
+			cells += max(column-len(values), 0) + 1
+			if cells > maxXLSXCells {
+				return nil, fmt.Errorf("invalid XLSX: more than %d cells", maxXLSXCells)
+			}
+			for len(values) < column {
+				values = append(values, "")
+			}
+
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				var index int
+				if _, err := fmt.Sscan(cell.Value, &index); err != nil || index < 0 || index >= len(shared) {
+					return nil, fmt.Errorf("invalid XLSX: bad shared string in cell %s", cell.Ref)
+				}
+				value = shared[index]
+			case "inlineStr":
+				value = cell.Inline.String()
+			}
+			values = append(values, value)
+		}
+		rows = append(rows, values)
+	}
+
+	return rows, nil
+}
+
+// Путь к первому листу книги из workbook.xml и его связей
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	workbook, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", errors.New("invalid XLSX: workbook not found")
+	}
+	var wb struct {
+		Sheets []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodePart(workbook, &wb); err != nil {
+		return "", err
+	}
+	if len(wb.Sheets) == 0 {
+		return "", errors.New("invalid XLSX: workbook has no sheets")
+	}
+
+	relsFile, ok := files["xl/_rels/workbook.xml.rels"]
+	if !ok {
+		return fallback, nil
+	}
+	var rels struct {
+		Items []xlsxRel `xml:"Relationship"`
+	}
+	if err := decodePart(relsFile, &rels); err != nil {
+		return "", err
+	}
+
+	for _, rel := range rels.Items {
+		if rel.ID != wb.Sheets[0].RelID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+
+	return fallback, nil
+}
+
+func decodePart(f *zip.File, v any) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("invalid XLSX: %w", err)
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(io.LimitReader(rc, maxXLSXPartSize)).Decode(v); err != nil {
+		return fmt.Errorf("invalid XLSX: %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// Номер столбца с нуля по ссылке ячейки (B12 -> 1). Столбцы дальше XFD отклоняются
+func columnIndex(ref string) (int, error) {
+	index := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A'+1)
+		if index > maxXLSXColumns {
+			return 0, fmt.Errorf("invalid XLSX: cell %s is beyond column XFD", ref)
+		}
+	}
+	if index == 0 {
+		return 0, fmt.Errorf("invalid XLSX: bad cell reference %s", ref)
+	}
+	return index - 1, nil
+}
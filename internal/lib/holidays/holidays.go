@@ -0,0 +1,214 @@
+package holidays
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// Наибольшая длина строки iCal после склейки переносов
+	maxICalLine = 1 << 20
+	// Наибольшая длина одного события iCal в днях: праздники и переносы длятся дни,
+	// а ошибка в DTEND не должна превращаться в годы выходных
+	maxEventDays = 31
+)
+
+// Особый день из файла календаря
+type Holiday struct {
+	Date time.Time
+	Name string
+	// Рабочий день, перенесенный на выходной
+	Working bool
+}
+
+// Разобрать файл по расширению: .ics - iCal, остальные - CSV
+func Parse(filename string, data []byte) ([]Holiday, error) {
+	if strings.EqualFold(filepath.Ext(filename), ".ics") || bytes.Contains(data, []byte("BEGIN:VCALENDAR")) {
+		return ParseICal(bytes.NewReader(data))
+	}
+	return ParseCSV(bytes.NewReader(data))
+}
+
+// CSV со строками "дата,название[,рабочий]". Разделитель запятая или точка с запятой,
+// дата YYYY-MM-DD или DD.MM.YYYY, строка заголовка пропускается
+func ParseCSV(r io.Reader) ([]Holiday, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if firstLine, _, _ := bytes.Cut(data, []byte("\n")); bytes.Count(firstLine, []byte(";")) >
+		bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	var result []Holiday
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		date, err := parseDate(record[0])
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		holiday := Holiday{Date: date}
+		if len(record) > 1 {
+			holiday.Name = strings.TrimSpace(record[1])
+		}
+		if len(record) > 2 {
+			holiday.Working = parseBool(record[2])
+		}
+		result = append(result, holiday)
+	}
+
+	return result, nil
+}
+
+// iCal: каждое событие VEVENT дает выходные дни с DTSTART по DTEND (не включая DTEND)
+func ParseICal(r io.Reader) ([]Holiday, error) {
+	var (
+		result []Holiday
+		inside bool
+		start  time.Time
+		end    time.Time
+		name   string
+	)
+
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range lines {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		// Параметры свойства (DTSTART;VALUE=DATE) не важны
+		key, _, _ = strings.Cut(strings.ToUpper(key), ";")
+
+		switch key {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				inside, start, end, name = true, time.Time{}, time.Time{}, ""
+			}
+		case "DTSTART", "DTEND":
+			if !inside {
+				continue
+			}
+			date, err := parseICalDate(value)
+			if err != nil {
+				return nil, err
+			}
+			if key == "DTSTART" {
+				start = date
+			} else {
+				end = date
+			}
+		case "SUMMARY":
+			if inside {
+				name = unescape(value)
+			}
+		case "END":
+			if !inside || !strings.EqualFold(value, "VEVENT") {
+				continue
+			}
+			inside = false
+			if start.IsZero() {
+				return nil, fmt.Errorf("event %q has no DTSTART", name)
+			}
+			if !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			if end.After(start.AddDate(0, 0, maxEventDays)) {
+				return nil, fmt.Errorf("event %q is longer than %d days", name, maxEventDays)
+			}
+			for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+				result = append(result, Holiday{Date: day, Name: name})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Склеить строки, перенесенные по правилам iCal (продолжение начинается с пробела или табуляции)
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxICalLine)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	// Слишком длинная строка обрывает чтение, и часть дней молча потерялась бы
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("invalid iCal: %w", err)
+	}
+
+	return lines, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{"2006-01-02", "02.01.2006"} {
+		if date, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// Дата iCal: 20260101 или 20260101T000000Z, время не учитывается
+func parseICalDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < len("20060102") {
+		return time.Time{}, fmt.Errorf("invalid iCal date %q", value)
+	}
+
+	date, err := time.ParseInLocation("20060102", value[:len("20060102")], time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid iCal date %q", value)
+	}
+	return date, nil
+}
+
+func parseBool(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "true", "yes", "да", "рабочий":
+		return true
+	}
+	return false
+}
+
+func unescape(value string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
@@ -0,0 +1,105 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Каналы доставки
+const (
+	ChannelEmail    = "email"
+	ChannelTelegram = "telegram"
+)
+
+// Таймаут одной отправки по умолчанию
+const defaultTimeout = 30 * time.Second
+
+// Получатель уведомления с контактами по каналам
+type Recipient struct {
+	Name           string
+	Email          string
+	TelegramChatID string
+}
+
+// Вложение (например, PDF-сводка)
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Уведомление: тема используется в письме, в Telegram уходит только текст
+type Message struct {
+	Subject     string
+	Text        string
+	Attachments []Attachment
+}
+
+// Канал доставки уведомлений
+type Notifier interface {
+	// Название канала (email, telegram)
+	Channel() string
+	// Адрес получателя в этом канале, пустая строка - получатель недоступен в канале
+	Address(to Recipient) string
+	// Отправить сообщение по адресу канала
+	Send(ctx context.Context, address string, msg Message) error
+}
+
+// Каналы, настроенные в переменных окружения:
+// SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD, SMTP_FROM, SMTP_TLS - почта;
+// TELEGRAM_BOT_TOKEN, TELEGRAM_API_URL - Telegram. Не настроенный канал пропускается
+func FromEnv() ([]Notifier, error) {
+	var notifiers []Notifier
+
+	if host := strings.TrimSpace(os.Getenv("SMTP_HOST")); host != "" {
+		port := 587
+		if value := strings.TrimSpace(os.Getenv("SMTP_PORT")); value != "" {
+			p, err := strconv.Atoi(value)
+			if err != nil || p < 1 || p > 65535 {
+				return nil, fmt.Errorf("invalid SMTP_PORT %q", value)
+			}
+			port = p
+		}
+
+		smtp, err := NewSMTP(SMTPConfig{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     strings.TrimSpace(os.Getenv("SMTP_FROM")),
+			TLS:      strings.TrimSpace(os.Getenv("SMTP_TLS")),
+		})
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, smtp)
+	}
+
+	if token := strings.TrimSpace(os.Getenv("TELEGRAM_BOT_TOKEN")); token != "" {
+		telegram, err := NewTelegram(TelegramConfig{
+			Token:   token,
+			BaseURL: strings.TrimSpace(os.Getenv("TELEGRAM_API_URL")),
+		})
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, telegram)
+	}
+
+	return notifiers, nil
+}
+
+var errNoAddress = errors.New("empty recipient address")
+
+// Контекст с таймаутом по умолчанию, если у ctx нет собственного срока
+func withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, defaultTimeout)
+}
@@ -0,0 +1,223 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// Минимальный SMTP-сервер: принимает одно письмо и отвечает reject на команду с префиксом failOn
+type fakeSMTP struct {
+	listener net.Listener
+	failOn   string
+	reject   string
+
+	from string
+	rcpt string
+	data chan string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	return &fakeSMTP{listener: listener, data: make(chan string, 1)}
+}
+
+func (f *fakeSMTP) port() int {
+	return f.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (f *fakeSMTP) serve() {
+	conn, err := f.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) {
+		io.WriteString(conn, line+"\r\n")
+	}
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+
+		if f.failOn != "" && strings.HasPrefix(cmd, f.failOn) {
+			reply(f.reject)
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			f.from = strings.TrimSpace(line)[len("MAIL FROM:"):]
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			f.rcpt = strings.TrimSpace(line)[len("RCPT TO:"):]
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			f.data <- data.String()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func newTestSMTP(t *testing.T, server *fakeSMTP) *SMTP {
+	t.Helper()
+
+	smtp, err := NewSMTP(SMTPConfig{
+		Host: "127.0.0.1",
+		Port: server.port(),
+		From: "Отчеты <reports@example.com>",
+		TLS:  SMTPPlain,
+	})
+	if err != nil {
+		t.Fatalf("NewSMTP: %v", err)
+	}
+	return smtp
+}
+
+func TestSMTPSend(t *testing.T) {
+	server := newFakeSMTP(t)
+	go server.serve()
+
+	msg := Message{
+		Subject: "Сводка за 2026-10-16",
+		Text:    "Отчетов: 5",
+		Attachments: []Attachment{{
+			Filename:    "report.pdf",
+			ContentType: "application/pdf",
+			Data:        []byte("%PDF-1.4 test"),
+		}},
+	}
+	if err := newTestSMTP(t, server).Send(context.Background(), "rep@example.com", msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	// Письмо передается после MAIL и RCPT, поэтому их значения уже записаны
+	data := <-server.data
+	if server.from != "<reports@example.com>" {
+		t.Errorf("MAIL FROM = %q", server.from)
+	}
+	if server.rcpt != "<rep@example.com>" {
+		t.Errorf("RCPT TO = %q", server.rcpt)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("read message: %v", err)
+	}
+
+	var dec mime.WordDecoder
+	subject, err := dec.DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("Subject = %q, %v", subject, err)
+	}
+	if to := parsed.Header.Get("To"); to != "<rep@example.com>" {
+		t.Errorf("To = %q", to)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q, %v", mediaType, err)
+	}
+
+	var parts []*multipart.Part
+	var bodies []string
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("next part: %v", err)
+		}
+		encoded, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("read part: %v", err)
+		}
+		body, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+		if err != nil {
+			t.Fatalf("decode part: %v", err)
+		}
+		parts = append(parts, part)
+		bodies = append(bodies, string(body))
+	}
+
+	if len(parts) != 2 {
+		t.Fatalf("got %d parts, want text and attachment", len(parts))
+	}
+	if bodies[0] != msg.Text {
+		t.Errorf("text = %q, want %q", bodies[0], msg.Text)
+	}
+	if name := parts[1].FileName(); name != "report.pdf" {
+		t.Errorf("attachment filename = %q", name)
+	}
+	if bodies[1] != string(msg.Attachments[0].Data) {
+		t.Errorf("attachment = %q", bodies[1])
+	}
+}
+
+func TestSMTPSendRejected(t *testing.T) {
+	server := newFakeSMTP(t)
+	server.failOn = "RCPT TO:"
+	server.reject = "550 5.1.1 No such user"
+	go server.serve()
+
+	err := newTestSMTP(t, server).Send(context.Background(), "nobody@example.com", Message{Text: "test"})
+	if err == nil {
+		t.Fatal("Send succeeded, want rcpt error")
+	}
+	if !strings.Contains(err.Error(), "rcpt to") || !strings.Contains(err.Error(), "No such user") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestSMTPSendDataRejected(t *testing.T) {
+	server := newFakeSMTP(t)
+	server.failOn = "DATA"
+	server.reject = "554 5.3.4 Message too big"
+	go server.serve()
+
+	err := newTestSMTP(t, server).Send(context.Background(), "rep@example.com", Message{Text: "test"})
+	if err == nil || !strings.Contains(err.Error(), "Message too big") {
+		t.Errorf("error = %v, want data rejection", err)
+	}
+}
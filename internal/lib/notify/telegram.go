@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// Адрес Bot API по умолчанию
+	defaultTelegramURL = "https://api.telegram.org"
+	// Наибольшая длина текста сообщения в Bot API
+	telegramMaxText = 4096
+)
+
+type TelegramConfig struct {
+	Token string
+	// Адрес Bot API, например локальный тестовый сервер; пустой - api.telegram.org
+	BaseURL string
+}
+
+// Отправка сообщений через Telegram Bot API
+type Telegram struct {
+	token   string
+	baseURL string
+	client  *http.Client
+}
+
+func NewTelegram(config TelegramConfig) (*Telegram, error) {
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = defaultTelegramURL
+	}
+	if u, err := url.Parse(baseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid TELEGRAM_API_URL %q", baseURL)
+	}
+
+	return &Telegram{
+		token:   config.Token,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: defaultTimeout},
+	}, nil
+}
+
+func (t *Telegram) Channel() string {
+	return ChannelTelegram
+}
+
+func (t *Telegram) Address(to Recipient) string {
+	return to.TelegramChatID
+}
+
+// Отправить текст сообщением, а вложения - отдельными документами
+func (t *Telegram) Send(ctx context.Context, address string, msg Message) error {
+	if address == "" {
+		return errNoAddress
+	}
+
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	text := []rune(msg.Text)
+	if len(text) > telegramMaxText {
+		text = append(text[:telegramMaxText-1], '…')
+	}
+
+	payload, err := json.Marshal(map[string]string{"chat_id": address, "text": string(text)})
+	if err != nil {
+		return err
+	}
+	if err := t.call(ctx, "sendMessage", "application/json", payload); err != nil {
+		return err
+	}
+
+	for _, attachment := range msg.Attachments {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		if err := mw.WriteField("chat_id", address); err != nil {
+			return err
+		}
+		part, err := mw.CreateFormFile("document", attachment.Filename)
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(attachment.Data); err != nil {
+			return err
+		}
+		if err := mw.Close(); err != nil {
+			return err
+		}
+
+		if err := t.call(ctx, "sendDocument", mw.FormDataContentType(), body.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Вызвать метод Bot API и проверить код и поле ok ответа
+func (t *Telegram) call(ctx context.Context, method, contentType string, body []byte) error {
+	endpoint := fmt.Sprintf("%s/bot%s/%s", t.baseURL, t.token, method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		// Ошибка клиента содержит URL с токеном бота, в текст попадает только причина
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram %s: %v", method, err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	// Прокси перед Bot API может ответить ошибкой не в формате JSON
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return fmt.Errorf("telegram %s: unexpected response (HTTP %d)", method, resp.StatusCode)
+	}
+	if !result.OK || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("telegram %s: %s (HTTP %d)", method, result.Description, resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testBotToken = "123456:secret-token"
+
+func newTestTelegram(t *testing.T, handler http.HandlerFunc) *Telegram {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	telegram, err := NewTelegram(TelegramConfig{Token: testBotToken, BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewTelegram: %v", err)
+	}
+	return telegram
+}
+
+func TestTelegramSend(t *testing.T) {
+	var message map[string]string
+	var document struct {
+		chatID   string
+		filename string
+		data     string
+	}
+
+	telegram := newTestTelegram(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bot" + testBotToken + "/sendMessage":
+			if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+				t.Errorf("decode sendMessage: %v", err)
+			}
+		case "/bot" + testBotToken + "/sendDocument":
+			file, header, err := r.FormFile("document")
+			if err != nil {
+				t.Errorf("sendDocument form: %v", err)
+				break
+			}
+			data, _ := io.ReadAll(file)
+			document.chatID = r.FormValue("chat_id")
+			document.filename = header.Filename
+			document.data = string(data)
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		io.WriteString(w, `{"ok":true,"result":{}}`)
+	})
+
+	msg := Message{
+		Subject: "не используется",
+		Text:    "Сводка: 5 отчетов",
+		Attachments: []Attachment{{
+			Filename: "report.pdf",
+			Data:     []byte("%PDF-1.4 test"),
+		}},
+	}
+	if err := telegram.Send(context.Background(), "-100200", msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if message["chat_id"] != "-100200" || message["text"] != msg.Text {
+		t.Errorf("sendMessage = %v", message)
+	}
+	if document.chatID != "-100200" || document.filename != "report.pdf" || document.data != "%PDF-1.4 test" {
+		t.Errorf("sendDocument = %+v", document)
+	}
+}
+
+func TestTelegramSendAPIError(t *testing.T) {
+	telegram := newTestTelegram(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"ok":false,"description":"Bad Request: chat not found"}`)
+	})
+
+	err := telegram.Send(context.Background(), "42", Message{Text: "test"})
+	if err == nil {
+		t.Fatal("Send succeeded, want API error")
+	}
+	if !strings.Contains(err.Error(), "chat not found") || !strings.Contains(err.Error(), "HTTP 400") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestTelegramSendServerError(t *testing.T) {
+	telegram := newTestTelegram(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream unavailable", http.StatusBadGateway)
+	})
+
+	err := telegram.Send(context.Background(), "42", Message{Text: "test"})
+	if err == nil {
+		t.Fatal("Send succeeded, want server error")
+	}
+	if !strings.Contains(err.Error(), "HTTP 502") {
+		t.Errorf("error = %v", err)
+	}
+	if strings.Contains(err.Error(), testBotToken) {
+		t.Errorf("error leaks bot token: %v", err)
+	}
+}
+
+func TestTelegramSendNon2xxWithOK(t *testing.T) {
+	telegram := newTestTelegram(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, `{"ok":true}`)
+	})
+
+	if err := telegram.Send(context.Background(), "42", Message{Text: "test"}); err == nil {
+		t.Error("Send succeeded on HTTP 500")
+	}
+}
@@ -0,0 +1,267 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// Размеры страницы A4 в пунктах
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// Документ PDF с одним встроенным шрифтом. Координаты страниц отсчитываются от левого верхнего угла
+type Document struct {
+	font   *Font
+	width  float64
+	height float64
+	pages  []*Page
+
+	// Использованные глифы и их символы для таблицы ширин и ToUnicode
+	used map[uint16]rune
+}
+
+// Страница документа
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+}
+
+// Создать документ со страницами размера width x height
+func NewDocument(font *Font, width, height float64) *Document {
+	return &Document{font: font, width: width, height: height, used: make(map[uint16]rune)}
+}
+
+// Размер страниц документа
+func (d *Document) Size() (width, height float64) {
+	return d.width, d.height
+}
+
+// Ширина текста в пунктах при размере size
+func (d *Document) TextWidth(text string, size float64) float64 {
+	return d.font.TextWidth(text, size)
+}
+
+// Добавить новую страницу
+func (d *Document) AddPage() *Page {
+	p := &Page{doc: d}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Вывести текст; y - базовая линия текста
+func (p *Page) Text(x, y, size float64, text string) {
+	var hex strings.Builder
+	for _, r := range text {
+		gid := p.doc.font.GlyphID(r)
+		if _, ok := p.doc.used[gid]; !ok {
+			p.doc.used[gid] = r
+		}
+		fmt.Fprintf(&hex, "%04X", gid)
+	}
+
+	fmt.Fprintf(&p.content, "BT /F1 %s Tf %s %s Td <%s> Tj ET\n",
+		num(size), num(x), num(p.doc.height-y), hex.String())
+}
+
+// Вывести текст, выровненный по правому краю x
+func (p *Page) TextRight(x, y, size float64, text string) {
+	p.Text(x-p.doc.TextWidth(text, size), y, size, text)
+}
+
+// Провести линию толщиной width
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n",
+		num(width), num(x1), num(p.doc.height-y1), num(x2), num(p.doc.height-y2))
+}
+
+// Залить прямоугольник серым цветом (0 - черный, 1 - белый); y - верхний край
+func (p *Page) FillRect(x, y, w, h, gray float64) {
+	fmt.Fprintf(&p.content, "%s g %s %s %s %s re f 0 g\n",
+		num(gray), num(x), num(p.doc.height-y-h), num(w), num(h))
+}
+
+// Записать документ
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	out := &pdfWriter{}
+	out.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	// Номера объектов: каталог, дерево страниц, шрифт (5 объектов), затем страницы
+	const (
+		catalogObj = iota + 1
+		pagesObj
+		fontObj
+		cidFontObj
+		descriptorObj
+		fontFileObj
+		toUnicodeObj
+		firstPageObj
+	)
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObj+2*i)
+	}
+
+	out.object(catalogObj, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObj))
+	out.object(pagesObj, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>",
+		strings.Join(kids, " "), len(d.pages)))
+
+	f := d.font
+	out.object(fontObj, fmt.Sprintf(
+		"<< /Type /Font /Subtype /Type0 /BaseFont /StatFont /Encoding /Identity-H "+
+			"/DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>", cidFontObj, toUnicodeObj))
+	out.object(cidFontObj, fmt.Sprintf(
+		"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /StatFont "+
+			"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+			"/FontDescriptor %d 0 R /CIDToGIDMap /Identity /DW %s /W [%s] >>",
+		descriptorObj, num(f.glyphWidth(0)), d.widths()))
+	out.object(descriptorObj, fmt.Sprintf(
+		"<< /Type /FontDescriptor /FontName /StatFont /Flags 32 /FontBBox [%s %s %s %s] "+
+			"/ItalicAngle %s /Ascent %s /Descent %s /CapHeight %s /StemV 80 /FontFile2 %d 0 R >>",
+		num(f.scale(f.bbox[0])), num(f.scale(f.bbox[1])), num(f.scale(f.bbox[2])), num(f.scale(f.bbox[3])),
+		num(f.italicAngle), num(f.scale(f.ascent)), num(f.scale(f.descent)), num(f.scale(f.capHeight)),
+		fontFileObj))
+	out.stream(fontFileObj, fmt.Sprintf("/Length1 %d", len(f.data)), f.data)
+	out.stream(toUnicodeObj, "", d.toUnicode())
+
+	for i, page := range d.pages {
+		pageObj := firstPageObj + 2*i
+		out.object(pageObj, fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] "+
+				"/Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+			pagesObj, num(d.width), num(d.height), fontObj, pageObj+1))
+		out.stream(pageObj+1, "", page.content.Bytes())
+	}
+
+	xref := out.buf.Len()
+	out.printf("xref\n0 %d\n0000000000 65535 f \n", len(out.offsets)+1)
+	for _, offset := range out.offsets {
+		out.printf("%010d 00000 n \n", offset)
+	}
+	out.printf("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(out.offsets)+1, catalogObj, xref)
+
+	if out.err != nil {
+		return 0, out.err
+	}
+	return out.buf.WriteTo(w)
+}
+
+// Документ целиком в виде байтов
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Массив ширин /W для использованных глифов
+func (d *Document) widths() string {
+	var b strings.Builder
+	for _, gid := range d.usedGlyphs() {
+		fmt.Fprintf(&b, "%d [%s] ", gid, num(d.font.glyphWidth(gid)))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// CMap ToUnicode для извлечения и поиска текста
+func (d *Document) toUnicode() []byte {
+	var b bytes.Buffer
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+
+	var glyphs []uint16
+	for _, gid := range d.usedGlyphs() {
+		if gid != 0 {
+			glyphs = append(glyphs, gid)
+		}
+	}
+	for len(glyphs) > 0 {
+		chunk := glyphs[:min(len(glyphs), 100)]
+		glyphs = glyphs[len(chunk):]
+
+		fmt.Fprintf(&b, "%d beginbfchar\n", len(chunk))
+		for _, gid := range chunk {
+			fmt.Fprintf(&b, "<%04X> <", gid)
+			for _, unit := range utf16.Encode([]rune{d.used[gid]}) {
+				fmt.Fprintf(&b, "%04X", unit)
+			}
+			b.WriteString(">\n")
+		}
+		b.WriteString("endbfchar\n")
+	}
+
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return b.Bytes()
+}
+
+func (d *Document) usedGlyphs() []uint16 {
+	glyphs := make([]uint16, 0, len(d.used))
+	for gid := range d.used {
+		glyphs = append(glyphs, gid)
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+	return glyphs
+}
+
+// Последовательная запись объектов с учетом смещений для таблицы xref
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+	err     error
+}
+
+func (w *pdfWriter) printf(format string, args ...any) {
+	fmt.Fprintf(&w.buf, format, args...)
+}
+
+func (w *pdfWriter) object(id int, body string) {
+	w.begin(id)
+	w.printf("%s\nendobj\n", body)
+}
+
+// Записать поток, сжатый FlateDecode
+func (w *pdfWriter) stream(id int, dict string, data []byte) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(data); err != nil && w.err == nil {
+		w.err = err
+	}
+	if err := zw.Close(); err != nil && w.err == nil {
+		w.err = err
+	}
+
+	w.begin(id)
+	w.printf("<< /Length %d /Filter /FlateDecode %s>>\nstream\n", compressed.Len(), dict)
+	w.buf.Write(compressed.Bytes())
+	w.printf("\nendstream\nendobj\n")
+}
+
+func (w *pdfWriter) begin(id int) {
+	if id != len(w.offsets)+1 && w.err == nil {
+		w.err = fmt.Errorf("pdf: object %d written out of order", id)
+	}
+	w.offsets = append(w.offsets, w.buf.Len())
+	w.printf("%d 0 obj\n", id)
+}
+
+// Число в синтаксисе PDF: не более двух знаков после запятой, без экспоненты
+func num(v float64) string {
+	v = math.Round(v*100) / 100
+	s := strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", v), "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,273 @@
+package pdf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Шрифт TrueType для встраивания в PDF: таблица символов и ширины глифов
+type Font struct {
+	data []byte
+
+	unitsPerEm  float64
+	ascent      float64
+	descent     float64
+	capHeight   float64
+	italicAngle float64
+	bbox        [4]float64
+
+	glyphs   map[rune]uint16
+	advances []uint16
+}
+
+// Шрифт по умолчанию: пакет font-dejavu в образе alpine
+const DefaultFontPath = "/usr/share/fonts/dejavu/DejaVuSans.ttf"
+
+// Загрузить шрифт из PDF_FONT_PATH (по умолчанию DefaultFontPath).
+// Шрифт должен содержать кириллицу, встроенные шрифты PDF ее не поддерживают
+func FontFromEnv() (*Font, error) {
+	path := strings.TrimSpace(os.Getenv("PDF_FONT_PATH"))
+	if path == "" {
+		path = DefaultFontPath
+	}
+	return LoadFont(path)
+}
+
+// Загрузить шрифт TrueType (.ttf) из файла
+func LoadFont(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read font: %w", err)
+	}
+	return ParseFont(data)
+}
+
+// Разобрать шрифт TrueType. Шрифты с контурами CFF (OTTO) и коллекции не поддерживаются
+func ParseFont(data []byte) (*Font, error) {
+	r := &fontReader{data: data}
+
+	switch r.u32(0) {
+	case 0x00010000, 0x74727565: // 1.0, 'true'
+	case 0x4f54544f: // 'OTTO'
+		return nil, errors.New("font: CFF outlines are not supported, use a TrueType font")
+	case 0x74746366: // 'ttcf'
+		return nil, errors.New("font: font collections are not supported")
+	default:
+		return nil, errors.New("font: not a TrueType font")
+	}
+
+	tables := make(map[string][]byte)
+	numTables := int(r.u16(4))
+	for i := 0; i < numTables; i++ {
+		record := 12 + 16*i
+		tag := string(r.bytes(record, 4))
+		offset, length := int(r.u32(record+8)), int(r.u32(record+12))
+		if r.err == nil && offset >= 0 && length >= 0 && offset+length <= len(data) {
+			tables[tag] = data[offset : offset+length]
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	for _, tag := range []string{"head", "hhea", "hmtx", "maxp", "cmap"} {
+		if tables[tag] == nil {
+			return nil, fmt.Errorf("font: missing %s table", tag)
+		}
+	}
+
+	f := &Font{data: data}
+
+	head := &fontReader{data: tables["head"]}
+	f.unitsPerEm = float64(head.u16(18))
+	f.bbox = [4]float64{
+		float64(head.i16(36)),
+		float64(head.i16(38)),
+		float64(head.i16(40)),
+		float64(head.i16(42)),
+	}
+	if head.err != nil || f.unitsPerEm == 0 {
+		return nil, errors.New("font: invalid head table")
+	}
+
+	hhea := &fontReader{data: tables["hhea"]}
+	f.ascent = float64(hhea.i16(4))
+	f.descent = float64(hhea.i16(6))
+	numHMetrics := int(hhea.u16(34))
+	if hhea.err != nil {
+		return nil, errors.New("font: invalid hhea table")
+	}
+
+	f.capHeight = f.ascent
+	if os2 := tables["OS/2"]; len(os2) >= 90 {
+		r := &fontReader{data: os2}
+		if r.u16(0) >= 2 {
+			f.capHeight = float64(r.i16(88))
+		}
+	}
+	if post := tables["post"]; len(post) >= 8 {
+		r := &fontReader{data: post}
+		f.italicAngle = float64(int32(r.u32(4))) / 65536
+	}
+
+	maxp := &fontReader{data: tables["maxp"]}
+	numGlyphs := int(maxp.u16(4))
+	if maxp.err != nil || numGlyphs == 0 {
+		return nil, errors.New("font: invalid maxp table")
+	}
+
+	hmtx := &fontReader{data: tables["hmtx"]}
+	f.advances = make([]uint16, numGlyphs)
+	for i := 0; i < numGlyphs; i++ {
+		if i < numHMetrics {
+			f.advances[i] = hmtx.u16(4 * i)
+		} else if i > 0 {
+			f.advances[i] = f.advances[i-1]
+		}
+	}
+	if hmtx.err != nil {
+		return nil, errors.New("font: invalid hmtx table")
+	}
+
+	glyphs, err := parseCmap(tables["cmap"], numGlyphs)
+	if err != nil {
+		return nil, err
+	}
+	f.glyphs = glyphs
+
+	return f, nil
+}
+
+// Номер глифа символа, 0 (.notdef) если символа нет в шрифте
+func (f *Font) GlyphID(r rune) uint16 {
+	return f.glyphs[r]
+}
+
+// Ширина текста в пунктах при размере size
+func (f *Font) TextWidth(text string, size float64) float64 {
+	var width float64
+	for _, r := range text {
+		width += f.glyphWidth(f.GlyphID(r))
+	}
+	return width * size / 1000
+}
+
+// Ширина глифа в единицах PDF (1/1000 размера шрифта)
+func (f *Font) glyphWidth(gid uint16) float64 {
+	if int(gid) >= len(f.advances) {
+		return 0
+	}
+	return f.scale(float64(f.advances[gid]))
+}
+
+func (f *Font) scale(v float64) float64 {
+	return v * 1000 / f.unitsPerEm
+}
+
+// Таблица символов: предпочитается полный Unicode (формат 12), затем BMP (формат 4)
+func parseCmap(data []byte, numGlyphs int) (map[rune]uint16, error) {
+	r := &fontReader{data: data}
+
+	var best, bestRank int
+	numTables := int(r.u16(2))
+	for i := 0; i < numTables; i++ {
+		record := 4 + 8*i
+		platform, encoding := r.u16(record), r.u16(record+2)
+		offset := int(r.u32(record + 4))
+		if r.err != nil || offset >= len(data) {
+			break
+		}
+
+		rank := 0
+		switch format := r.u16(offset); {
+		case format == 12 && (platform == 3 && encoding == 10 || platform == 0):
+			rank = 3
+		case format == 4 && (platform == 3 && encoding == 1 || platform == 0):
+			rank = 2
+		}
+		if rank > bestRank {
+			best, bestRank = offset, rank
+		}
+	}
+	if bestRank == 0 {
+		return nil, errors.New("font: no Unicode cmap subtable")
+	}
+
+	glyphs := make(map[rune]uint16)
+	add := func(c rune, gid int) {
+		if gid > 0 && gid < numGlyphs {
+			glyphs[c] = uint16(gid)
+		}
+	}
+
+	if r.u16(best) == 12 {
+		numGroups := int(r.u32(best + 12))
+		for i := 0; i < numGroups && r.err == nil; i++ {
+			group := best + 16 + 12*i
+			start, end, gid := r.u32(group), r.u32(group+4), r.u32(group+8)
+			if end < start || end > 0x10FFFF {
+				continue
+			}
+			for c := start; c <= end; c++ {
+				add(rune(c), int(gid+c-start))
+			}
+		}
+	} else {
+		segCount := int(r.u16(best+6)) / 2
+		endCodes := best + 14
+		startCodes := endCodes + 2*segCount + 2
+		idDeltas := startCodes + 2*segCount
+		idRangeOffsets := idDeltas + 2*segCount
+
+		for i := 0; i < segCount && r.err == nil; i++ {
+			start, end := int(r.u16(startCodes+2*i)), int(r.u16(endCodes+2*i))
+			delta := int(r.u16(idDeltas + 2*i))
+			rangeOffset := int(r.u16(idRangeOffsets + 2*i))
+
+			for c := start; c <= end && c != 0xFFFF; c++ {
+				if rangeOffset == 0 {
+					add(rune(c), (c+delta)&0xFFFF)
+					continue
+				}
+				g := int(r.u16(idRangeOffsets + 2*i + rangeOffset + 2*(c-start)))
+				if g != 0 {
+					add(rune(c), (g+delta)&0xFFFF)
+				}
+			}
+		}
+	}
+	if r.err != nil {
+		return nil, errors.New("font: invalid cmap table")
+	}
+
+	return glyphs, nil
+}
+
+// Чтение big-endian значений с проверкой границ: при выходе за границы запоминается ошибка
+type fontReader struct {
+	data []byte
+	err  error
+}
+
+func (r *fontReader) bytes(off, n int) []byte {
+	if off < 0 || off+n > len(r.data) {
+		r.err = errors.New("font: unexpected end of data")
+		return make([]byte, n)
+	}
+	return r.data[off : off+n]
+}
+
+func (r *fontReader) u16(off int) uint16 {
+	return binary.BigEndian.Uint16(r.bytes(off, 2))
+}
+
+func (r *fontReader) i16(off int) int16 {
+	return int16(r.u16(off))
+}
+
+func (r *fontReader) u32(off int) uint32 {
+	return binary.BigEndian.Uint32(r.bytes(off, 4))
+}
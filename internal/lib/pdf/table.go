@@ -0,0 +1,152 @@
+package pdf
+
+// Выравнивание текста в столбце
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignRight
+)
+
+// Столбец таблицы
+type Column struct {
+	Title string
+	Width float64
+	Align Align
+}
+
+// Заголовок группы соседних столбцов (например, продукта над столбцами план/факт/разница)
+type ColumnGroup struct {
+	Title string
+	Span  int
+}
+
+// Таблица: необязательная строка групп, заголовки столбцов, строки и выделенная итоговая строка
+type Table struct {
+	Groups  []ColumnGroup
+	Columns []Column
+	Rows    [][]string
+	Footer  []string
+}
+
+// Число строк таблицы с учетом заголовков и итога
+func (t *Table) Lines() int {
+	lines := 1 + len(t.Rows)
+	if len(t.Groups) > 0 {
+		lines++
+	}
+	if t.Footer != nil {
+		lines++
+	}
+	return lines
+}
+
+// Нарисовать таблицу с левым верхним углом в (x, y), вернуть y под таблицей
+func (p *Page) Table(x, y, size, rowHeight float64, t Table) float64 {
+	var width float64
+	for _, col := range t.Columns {
+		width += col.Width
+	}
+	padding := size / 3
+	baseline := (rowHeight + size*0.7) / 2
+
+	// Строка текста по столбцам с заливкой fill (1 - без заливки)
+	row := func(cells []string, fill float64) {
+		if fill < 1 {
+			p.FillRect(x, y, width, rowHeight, fill)
+		}
+		cx := x
+		for i, col := range t.Columns {
+			if i < len(cells) {
+				text := p.doc.Fit(cells[i], col.Width-2*padding, size)
+				if col.Align == AlignRight {
+					p.TextRight(cx+col.Width-padding, y+baseline, size, text)
+				} else {
+					p.Text(cx+padding, y+baseline, size, text)
+				}
+			}
+			cx += col.Width
+		}
+		y += rowHeight
+		p.Line(x, y, x+width, y, 0.3)
+	}
+
+	p.Line(x, y, x+width, y, 0.5)
+	top := y
+
+	if len(t.Groups) > 0 {
+		p.FillRect(x, y, width, rowHeight, 0.85)
+		cx, col := x, 0
+		for _, group := range t.Groups {
+			var groupWidth float64
+			for i := col; i < col+group.Span && i < len(t.Columns); i++ {
+				groupWidth += t.Columns[i].Width
+			}
+			if group.Title != "" {
+				text := p.doc.Fit(group.Title, groupWidth-2*padding, size)
+				p.Text(cx+(groupWidth-p.doc.TextWidth(text, size))/2, y+baseline, size, text)
+			}
+			cx += groupWidth
+			col += group.Span
+		}
+		y += rowHeight
+		p.Line(x, y, x+width, y, 0.3)
+	}
+
+	titles := make([]string, len(t.Columns))
+	for i, col := range t.Columns {
+		titles[i] = col.Title
+	}
+	row(titles, 0.85)
+
+	for i, cells := range t.Rows {
+		fill := 1.0
+		if i%2 == 1 {
+			fill = 0.96
+		}
+		row(cells, fill)
+	}
+
+	if t.Footer != nil {
+		p.Line(x, y, x+width, y, 0.8)
+		row(t.Footer, 0.9)
+	}
+
+	// Вертикальные границы: по краям таблицы и между группами столбцов
+	p.Line(x, top, x, y, 0.5)
+	cx := x
+	if len(t.Groups) > 0 {
+		col := 0
+		for _, group := range t.Groups {
+			for i := col; i < col+group.Span && i < len(t.Columns); i++ {
+				cx += t.Columns[i].Width
+			}
+			col += group.Span
+			p.Line(cx, top, cx, y, 0.5)
+		}
+	} else {
+		for _, col := range t.Columns {
+			cx += col.Width
+			p.Line(cx, top, cx, y, 0.3)
+		}
+	}
+	p.Line(x+width, top, x+width, y, 0.5)
+
+	return y
+}
+
+// Обрезать текст с многоточием, чтобы он поместился в ширину width
+func (d *Document) Fit(text string, width, size float64) string {
+	if d.TextWidth(text, size) <= width {
+		return text
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		if s := string(runes) + "…"; d.TextWidth(s, size) <= width {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,154 @@
+package retention
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/auth"
+)
+
+const (
+	// Срок хранения по умолчанию, если TRUNCATE_INTERVAL не задан
+	defaultDays = 30
+	// Верхняя граница срока хранения, защищает от опечаток в конфиге
+	maxDays = 3650
+)
+
+// Политика хранения ежедневных отчетов: общий срок и переопределения по регионам
+type Policy struct {
+	defaultDays int
+	regionDays  map[uint]int
+}
+
+// Информация о сроке хранения для региона
+type Info struct {
+	Days       int    `json:"days"`
+	OldestDate string `json:"oldest_date"`
+}
+
+// Загрузить политику из переменных окружения:
+// TRUNCATE_INTERVAL - общий срок (например 30d),
+// REGION_TRUNCATE_INTERVALS - переопределения по названию региона (например Тихорецк=60d,Санкт-Петербург=14d)
+func FromEnv(regions []auth.Region) (*Policy, error) {
+	return Parse(os.Getenv("TRUNCATE_INTERVAL"), os.Getenv("REGION_TRUNCATE_INTERVALS"), regions)
+}
+
+// Разобрать и проверить политику хранения. Переопределения для неизвестных регионов считаются ошибкой
+func Parse(defaultInterval, regionIntervals string, regions []auth.Region) (*Policy, error) {
+	policy := &Policy{
+		defaultDays: defaultDays,
+		regionDays:  make(map[uint]int),
+	}
+
+	if strings.TrimSpace(defaultInterval) != "" {
+		days, err := ParseInterval(defaultInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRUNCATE_INTERVAL: %w", err)
+		}
+		policy.defaultDays = days
+	}
+
+	regionsByName := make(map[string]uint, len(regions))
+	for _, region := range regions {
+		regionsByName[region.Name] = region.ID
+	}
+
+	for _, item := range strings.Split(regionIntervals, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, interval, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid REGION_TRUNCATE_INTERVALS item %q: expected region=interval", item)
+		}
+
+		regionID, ok := regionsByName[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("invalid REGION_TRUNCATE_INTERVALS: unknown region %q", name)
+		}
+
+		days, err := ParseInterval(interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REGION_TRUNCATE_INTERVALS for %q: %w", name, err)
+		}
+		policy.regionDays[regionID] = days
+	}
+
+	return policy, nil
+}
+
+// Разобрать интервал в днях: 30d, 4w или длительность Go, кратная суткам (720h)
+func ParseInterval(interval string) (int, error) {
+	interval = strings.TrimSpace(interval)
+
+	var days int
+	switch {
+	case strings.HasSuffix(interval, "d"), strings.HasSuffix(interval, "w"):
+		n, err := strconv.Atoi(interval[:len(interval)-1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid interval %q", interval)
+		}
+		days = n
+		if strings.HasSuffix(interval, "w") {
+			days = n * 7
+		}
+	default:
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return 0, fmt.Errorf("invalid interval %q", interval)
+		}
+		if d%(24*time.Hour) != 0 {
+			return 0, fmt.Errorf("interval %q must be a whole number of days", interval)
+		}
+		days = int(d / (24 * time.Hour))
+	}
+
+	if days < 1 || days > maxDays {
+		return 0, fmt.Errorf("interval %q must be between 1 and %d days", interval, maxDays)
+	}
+
+	return days, nil
+}
+
+// Срок хранения для региона в днях. Регионы без переопределения, в том числе
+// созданные после запуска, хранятся общий срок
+func (p *Policy) Days(regionID uint) int {
+	if days, ok := p.regionDays[regionID]; ok {
+		return days
+	}
+	return p.defaultDays
+}
+
+// Наибольший срок хранения среди всех регионов
+func (p *Policy) MaxDays() int {
+	days := p.defaultDays
+	for _, d := range p.regionDays {
+		days = max(days, d)
+	}
+	return days
+}
+
+// Первая дата, которая еще хранится для региона. Отчеты за более ранние даты удаляются
+func (p *Policy) Cutoff(regionID uint, now time.Time) time.Time {
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	return today.AddDate(0, 0, -p.Days(regionID))
+}
+
+// Проверить, что дата еще хранится для региона
+func (p *Policy) IsRetained(regionID uint, date time.Time, now time.Time) bool {
+	return !date.Before(p.Cutoff(regionID, now))
+}
+
+// Срок хранения и самая ранняя доступная дата для региона
+func (p *Policy) Info(regionID uint, now time.Time) Info {
+	return Info{
+		Days:       p.Days(regionID),
+		OldestDate: p.Cutoff(regionID, now).Format("2006-01-02"),
+	}
+}
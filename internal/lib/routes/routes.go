@@ -12,6 +12,20 @@ func SetupRoutes(
 	r *gin.Engine,
 	authController *auth.AuthController,
 	djnHandler *handler.DjnHandler,
+	productHandler *handler.ProductHandler,
+	metricHandler *handler.MetricHandler,
+	archiveHandler *handler.ArchiveHandler,
+	adminStatsHandler *handler.AdminStatsHandler,
+	revisionHandler *handler.RevisionHandler,
+	planHandler *handler.PlanHandler,
+	calendarHandler *handler.CalendarHandler,
+	pacingHandler *handler.PacingHandler,
+	leaderboardHandler *handler.LeaderboardHandler,
+	scoringHandler *handler.ScoringHandler,
+	bonusHandler *handler.BonusHandler,
+	dailyReportHandler *handler.DailyReportHandler,
+	digestHandler *handler.DigestHandler,
+	missingHandler *handler.MissingHandler,
 ) {
 	r.Static("/images", "./web/images")
 	r.Static("/static", "./web/static")
@@ -36,6 +50,19 @@ func SetupRoutes(
 			djinRoutes.GET("/stat", djnHandler.GetStatByRegion)
 			djinRoutes.GET("/total", djnHandler.GetInfo)
 			djinRoutes.GET("/month", djnHandler.GetStatByMonth)
+			djinRoutes.GET("/range", djnHandler.GetStatsByRange)
+			djinRoutes.GET("/export", djnHandler.ExportStats)
+			djinRoutes.GET("/report.pdf", dailyReportHandler.GetDailyReport)
+			djinRoutes.GET("/missing", missingHandler.GetMissing)
+			djinRoutes.GET("/products", productHandler.GetActiveProducts)
+			djinRoutes.GET("/metrics", metricHandler.GetRegionMetrics)
+			djinRoutes.GET("/retention", djnHandler.GetRetention)
+			djinRoutes.GET("/backfill", djnHandler.GetBackfill)
+			djinRoutes.GET("/plan", planHandler.GetDailyPlan)
+			djinRoutes.GET("/calendar", calendarHandler.GetMonthCalendar)
+			djinRoutes.GET("/pacing", pacingHandler.GetPacing)
+			djinRoutes.GET("/leaderboard", leaderboardHandler.GetLeaderboard)
+			djinRoutes.GET("/archive", archiveHandler.GetMonthlyArchive)
 		}
 
 		// Административные маршруты (только для администраторов)
@@ -51,6 +78,79 @@ func SetupRoutes(
 			// Получение списка регионов
 			adminRoutes.GET("/regions", authController.GetRegions)
 
+			// Каталог продуктов
+			adminRoutes.GET("/products", productHandler.GetProducts)
+			adminRoutes.POST("/products", productHandler.CreateProduct)
+			adminRoutes.PUT("/products/:id", productHandler.UpdateProduct)
+			adminRoutes.DELETE("/products/:id", productHandler.DeleteProduct)
+
+			// Определения KPI-счетчиков
+			adminRoutes.GET("/metrics", metricHandler.GetMetrics)
+			adminRoutes.POST("/metrics", metricHandler.CreateMetric)
+			adminRoutes.PUT("/metrics/:id", metricHandler.UpdateMetric)
+			adminRoutes.DELETE("/metrics/:id", metricHandler.DeleteMetric)
+
+			// Месячные цели по продуктам для пользователей и регионов
+			adminRoutes.GET("/plans", planHandler.GetTargets)
+			adminRoutes.PUT("/plans", planHandler.SaveTarget)
+			adminRoutes.DELETE("/plans/:id", planHandler.DeleteTarget)
+
+			// Календарь рабочих дней: выходные, праздники и импорт из CSV/iCal
+			adminRoutes.GET("/calendar", calendarHandler.GetCalendar)
+			adminRoutes.PUT("/calendar/weekends", calendarHandler.SetWeekends)
+			adminRoutes.POST("/calendar/days", calendarHandler.SaveDays)
+			adminRoutes.DELETE("/calendar/days/:id", calendarHandler.DeleteDay)
+			adminRoutes.POST("/calendar/import", calendarHandler.ImportHolidays)
+
+			// Формулы сводной оценки KPI по регионам
+			adminRoutes.GET("/scoring", scoringHandler.GetFormulas)
+			adminRoutes.PUT("/scoring", scoringHandler.SaveFormula)
+			adminRoutes.DELETE("/scoring/:id", scoringHandler.DeleteFormula)
+
+			// Правила и месячные расчеты премий
+			adminRoutes.GET("/bonus/rules", bonusHandler.GetRules)
+			adminRoutes.POST("/bonus/rules", bonusHandler.CreateRule)
+			adminRoutes.PUT("/bonus/rules/:id", bonusHandler.UpdateRule)
+			adminRoutes.DELETE("/bonus/rules/:id", bonusHandler.DeleteRule)
+			adminRoutes.GET("/bonus/runs", bonusHandler.GetRuns)
+			adminRoutes.POST("/bonus/runs", bonusHandler.Calculate)
+			adminRoutes.GET("/bonus/runs/:id", bonusHandler.GetRun)
+			adminRoutes.DELETE("/bonus/runs/:id", bonusHandler.DeleteRun)
+			adminRoutes.POST("/bonus/runs/:id/lock", bonusHandler.LockRun)
+			adminRoutes.GET("/bonus/runs/:id/export", bonusHandler.ExportRun)
+
+			// Статистика по всем регионам и отчеты выбранного региона
+			adminRoutes.GET("/stats", adminStatsHandler.GetAllRegionalStats)
+			adminRoutes.GET("/stats/regions/:id", adminStatsHandler.GetRegionReports)
+			adminRoutes.GET("/stats/pacing", pacingHandler.GetRegionPacing)
+			adminRoutes.GET("/stats/leaderboard", leaderboardHandler.GetCompanyLeaderboard)
+			adminRoutes.GET("/stats/export", djnHandler.ExportRegionStats)
+			adminRoutes.GET("/stats/report.pdf", dailyReportHandler.GetRegionDailyReport)
+
+			// Ежедневные сводки и журнал уведомлений
+			adminRoutes.GET("/digest", digestHandler.GetDigest)
+			adminRoutes.POST("/digest/send", digestHandler.SendDigest)
+			adminRoutes.GET("/notifications", digestHandler.GetNotifications)
+
+			// Несданные отчеты и напоминания
+			adminRoutes.GET("/stats/missing", missingHandler.GetRegionMissing)
+			adminRoutes.POST("/missing/remind", missingHandler.SendReminders)
+
+			// Создание и исправление отчетов любого пользователя
+			adminRoutes.POST("/reports", djnHandler.CreateReport)
+			adminRoutes.POST("/reports/import", djnHandler.ImportStats)
+			adminRoutes.PATCH("/reports/:id", djnHandler.UpdateReport)
+			adminRoutes.DELETE("/reports/:id", djnHandler.DeleteReport)
+
+			// История изменений отчетов и восстановление ревизий
+			adminRoutes.GET("/reports/:id/history", revisionHandler.GetReportHistory)
+			adminRoutes.GET("/revisions", revisionHandler.GetRevisions)
+			adminRoutes.POST("/revisions/:id/restore", revisionHandler.RestoreRevision)
+
+			// Пересборка агрегированной статистики из БД
+			adminRoutes.POST("/stats/rebuild", djnHandler.RebuildStats)
+			adminRoutes.POST("/stats/reconcile", djnHandler.ReconcileStats)
+
 			// Административная панель
 			adminRoutes.GET("/panel", func(c *gin.Context) {
 				c.HTML(http.StatusOK, "admin_panel.html", gin.H{
@@ -0,0 +1,139 @@
+package schedule
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/auth"
+)
+
+// Значение переопределения, отключающее событие для региона
+const off = "off"
+
+// Время суток HH:MM в часовом поясе сервера
+type Clock struct {
+	Hour   int
+	Minute int
+}
+
+// Разобрать время суток HH:MM
+func ParseClock(value string) (Clock, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return Clock{}, fmt.Errorf("invalid time %q: expected HH:MM", value)
+	}
+	return Clock{Hour: t.Hour(), Minute: t.Minute()}, nil
+}
+
+func (c Clock) String() string {
+	return fmt.Sprintf("%02d:%02d", c.Hour, c.Minute)
+}
+
+// Момент этого времени в тот же день, что и day
+func (c Clock) On(day time.Time) time.Time {
+	year, month, date := day.Date()
+	return time.Date(year, month, date, c.Hour, c.Minute, 0, 0, day.Location())
+}
+
+// Время ежедневного события по регионам: общее и переопределения по названию региона.
+// Регионы без переопределения, в том числе созданные после запуска, получают общее время
+type Times struct {
+	defaultClock *Clock
+	// Переопределения по региону, nil - событие для региона отключено
+	regions map[uint]*Clock
+}
+
+// Загрузить расписание из переменных окружения: defaultVar - общее время (пустое - отключено),
+// regionVar - переопределения по названию региона (например Тихорецк=19:00,Санкт-Петербург=off)
+func FromEnv(defaultVar, regionVar string, regions []auth.Region) (*Times, error) {
+	return Parse(defaultVar, regionVar, os.Getenv(defaultVar), os.Getenv(regionVar), regions)
+}
+
+// Разобрать и проверить расписание. Имена переменных используются в тексте ошибок,
+// переопределения для неизвестных регионов считаются ошибкой
+func Parse(defaultVar, regionVar, defaultValue, regionValues string, regions []auth.Region) (*Times, error) {
+	times := &Times{regions: make(map[uint]*Clock)}
+
+	if strings.TrimSpace(defaultValue) != "" {
+		clock, err := ParseClock(defaultValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", defaultVar, err)
+		}
+		times.defaultClock = &clock
+	}
+
+	regionsByName := make(map[string]uint, len(regions))
+	for _, region := range regions {
+		regionsByName[region.Name] = region.ID
+	}
+
+	for _, item := range strings.Split(regionValues, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s item %q: expected region=HH:MM", regionVar, item)
+		}
+
+		regionID, ok := regionsByName[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("invalid %s: unknown region %q", regionVar, name)
+		}
+
+		if strings.TrimSpace(value) == off {
+			times.regions[regionID] = nil
+			continue
+		}
+
+		clock, err := ParseClock(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s for %q: %w", regionVar, name, err)
+		}
+		times.regions[regionID] = &clock
+	}
+
+	return times, nil
+}
+
+// Время события для региона, false - для региона событие отключено
+func (t *Times) At(regionID uint) (Clock, bool) {
+	clock, ok := t.regions[regionID]
+	if !ok {
+		clock = t.defaultClock
+	}
+	if clock == nil {
+		return Clock{}, false
+	}
+	return *clock, true
+}
+
+// Включено ли событие хотя бы для одного региона
+func (t *Times) Enabled() bool {
+	if t.defaultClock != nil {
+		return true
+	}
+	for _, clock := range t.regions {
+		if clock != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Регионы из списка, для которых событие включено
+func (t *Times) RegionIDs(regions []auth.Region) []uint {
+	ids := make([]uint, 0, len(regions))
+	for _, region := range regions {
+		if _, ok := t.At(region.ID); ok {
+			ids = append(ids, region.ID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
@@ -0,0 +1,88 @@
+package scoring
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Wladim1r/statcounter/internal/models"
+)
+
+// Посчитать оценку отчета или суммы отчетов за период по слагаемым формулы.
+// Для периода процент плана берется от суммарных плана и факта
+func Evaluate(terms []models.ScoreTerm, stat models.StatDaily) float64 {
+	var score float64
+
+	for _, term := range terms {
+		value := Value(term, stat)
+
+		if term.Threshold != nil && value < *term.Threshold {
+			continue
+		}
+		if term.Cap != nil && value > *term.Cap {
+			value = *term.Cap
+		}
+
+		score += term.Weight*value + term.Bonus
+	}
+
+	return math.Round(score*100) / 100
+}
+
+// Значение отчета для слагаемого, отсутствующий продукт или счетчик дает 0
+func Value(term models.ScoreTerm, stat models.StatDaily) float64 {
+	if term.Source == models.ScoreSourceMetric {
+		if m := stat.Metric(term.Code); m != nil {
+			return float64(m.Value)
+		}
+		return 0
+	}
+
+	p := stat.Product(term.Code)
+	if p == nil {
+		return 0
+	}
+
+	switch term.Source {
+	case models.ScoreSourcePercent:
+		if p.Plan == 0 {
+			return 0
+		}
+		return p.Fact / p.Plan * 100
+	case models.ScoreSourcePlan:
+		return p.Plan
+	case models.ScoreSourceFact:
+		return p.Fact
+	case models.ScoreSourceDif:
+		return p.Dif
+	}
+
+	return 0
+}
+
+// Проверить слагаемые: известный источник, код из products или metrics, порог не выше ограничения
+func Validate(terms []models.ScoreTerm, products map[string]bool, metrics map[string]bool) error {
+	if len(terms) == 0 {
+		return fmt.Errorf("formula must have at least one term")
+	}
+
+	for i, term := range terms {
+		switch term.Source {
+		case models.ScoreSourcePercent, models.ScoreSourcePlan, models.ScoreSourceFact, models.ScoreSourceDif:
+			if !products[term.Code] {
+				return fmt.Errorf("term %d: unknown product %q", i+1, term.Code)
+			}
+		case models.ScoreSourceMetric:
+			if !metrics[term.Code] {
+				return fmt.Errorf("term %d: unknown metric %q", i+1, term.Code)
+			}
+		default:
+			return fmt.Errorf("term %d: source must be percent, plan, fact, dif or metric", i+1)
+		}
+
+		if term.Cap != nil && term.Threshold != nil && *term.Threshold > *term.Cap {
+			return fmt.Errorf("term %d: threshold is greater than cap", i+1)
+		}
+	}
+
+	return nil
+}
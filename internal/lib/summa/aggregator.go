@@ -0,0 +1,82 @@
+package summa
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Wladim1r/statcounter/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// Агрегаты в памяти процесса: быстро, но только для одного экземпляра приложения
+	AggregatorMemory = "memory"
+	// Агрегаты считаются SQL-запросами к БД и одинаковы на всех экземплярах
+	AggregatorPostgres = "postgres"
+)
+
+// Источник агрегированной статистики по регионам и датам
+type Aggregator interface {
+	// Агрегат и количество отчетов региона за дату
+	RegionStats(regionID uint, date string) (models.StatDaily, int, error)
+	// Агрегаты и количество отчетов всех регионов за дату
+	AllRegionStats(date string) (map[uint]models.StatDaily, map[uint]int, error)
+	// Сумма по всем регионам за дату
+	TotalStats(date string) (models.StatDaily, int, error)
+	// Учесть закоммиченные события outbox
+	Dispatch() error
+	// Полностью пересобрать агрегаты из БД
+	Rebuild() error
+	// Сверить агрегаты с БД, возвращает число исправленных расхождений
+	Reconcile() (int, error)
+}
+
+// Создать агрегатор по названию (пустое - в памяти)
+func NewAggregator(kind string, db *gorm.DB) (Aggregator, error) {
+	switch strings.TrimSpace(kind) {
+	case "", AggregatorMemory:
+		return &memoryAggregator{db: db}, nil
+	case AggregatorPostgres:
+		return &postgresAggregator{db: db}, nil
+	default:
+		return nil, fmt.Errorf(
+			"unknown aggregator %q: expected %s or %s",
+			kind,
+			AggregatorMemory,
+			AggregatorPostgres,
+		)
+	}
+}
+
+// Агрегатор поверх общего состояния пакета, обновляется событиями outbox
+type memoryAggregator struct {
+	db *gorm.DB
+}
+
+func (a *memoryAggregator) RegionStats(regionID uint, date string) (models.StatDaily, int, error) {
+	stat, quantity := GetStatsForRegion(regionID, date)
+	return stat, quantity, nil
+}
+
+func (a *memoryAggregator) AllRegionStats(
+	date string,
+) (map[uint]models.StatDaily, map[uint]int, error) {
+	return GetAllRegionalStats(date), GetAllQuantities(date), nil
+}
+
+func (a *memoryAggregator) TotalStats(date string) (models.StatDaily, int, error) {
+	stat, quantity := GetTotalStats(date)
+	return stat, quantity, nil
+}
+
+func (a *memoryAggregator) Dispatch() error {
+	return Dispatch(a.db)
+}
+
+func (a *memoryAggregator) Rebuild() error {
+	return InitializeFromDB(a.db)
+}
+
+func (a *memoryAggregator) Reconcile() (int, error) {
+	return Reconcile(a.db)
+}
@@ -0,0 +1,348 @@
+package summa
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// Сколько событий забирается из outbox за один проход
+	dispatchBatch = 500
+	// Обработанные события хранятся неделю для разбора инцидентов
+	processedEventsTTL = 7 * 24 * time.Hour
+)
+
+// Сериализует применение событий, пересборку и сверку агрегатов
+var dispatchMu sync.Mutex
+
+// Записать событие изменения отчета в outbox в транзакции tx.
+// oldStat пуст при добавлении, newStat - при удалении
+func RecordEvent(tx *gorm.DB, oldStat, newStat *models.StatDaily) error {
+	event := models.StatEvent{}
+
+	switch {
+	case oldStat == nil:
+		event.Kind = models.StatEventAdd
+	case newStat == nil:
+		event.Kind = models.StatEventRemove
+	default:
+		event.Kind = models.StatEventUpdate
+	}
+
+	ref := newStat
+	if ref == nil {
+		ref = oldStat
+	}
+	event.RegionID = ref.RegionID
+	event.Date = normalizeDate(ref.Date)
+
+	var err error
+	if oldStat != nil {
+		if event.OldData, err = json.Marshal(oldStat); err != nil {
+			return fmt.Errorf("failed to encode old state: %w", err)
+		}
+	}
+	if newStat != nil {
+		if event.NewData, err = json.Marshal(newStat); err != nil {
+			return fmt.Errorf("failed to encode new state: %w", err)
+		}
+	}
+
+	return tx.Create(&event).Error
+}
+
+// Записать в outbox удаление отчетов региона раньше cutoffDate
+func RecordPurge(tx *gorm.DB, regionID uint, cutoffDate time.Time) error {
+	return tx.Create(&models.StatEvent{
+		Kind:     models.StatEventPurge,
+		RegionID: regionID,
+		Date:     cutoffDate.Format("2006-01-02"),
+	}).Error
+}
+
+// Применить к агрегатам все закоммиченные и еще не обработанные события.
+// События помечаются обработанными до применения в памяти: если процесс упадет
+// между этими шагами, агрегаты все равно будут заново собраны из БД при запуске
+func Dispatch(db *gorm.DB) error {
+	dispatchMu.Lock()
+	defer dispatchMu.Unlock()
+
+	for {
+		var events []models.StatEvent
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			events, err = claimEvents(tx, dispatchBatch)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to claim stat events: %w", err)
+		}
+
+		for _, event := range events {
+			applyEvent(event)
+		}
+
+		if len(events) < dispatchBatch {
+			return nil
+		}
+	}
+}
+
+// Инициализировать статистику из БД (при запуске приложения или по запросу администратора).
+// Текущие агрегаты полностью заменяются суммой всех сохраненных отчетов. Отчеты и outbox
+// читаются из одного снимка, поэтому видимые в нем события уже учтены и помечаются обработанными
+func InitializeFromDB(db *gorm.DB) error {
+	dispatchMu.Lock()
+	defer dispatchMu.Unlock()
+
+	var newStats map[statKey]models.StatDaily
+	var newQuantities map[statKey]int
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if newStats, newQuantities, err = loadTotals(tx); err != nil {
+			return err
+		}
+
+		return tx.Model(&models.StatEvent{}).
+			Where("processed_at IS NULL").
+			Update("processed_at", time.Now()).Error
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return fmt.Errorf("failed to load stats from DB: %w", err)
+	}
+
+	regionalStats.mu.Lock()
+	defer regionalStats.mu.Unlock()
+
+	regionalStats.stats = newStats
+	regionalStats.quantities = newQuantities
+
+	return nil
+}
+
+// Сверить агрегаты с БД и исправить расхождения. Возвращает число исправленных пар регион-дата
+func Reconcile(db *gorm.DB) (int, error) {
+	dispatchMu.Lock()
+	defer dispatchMu.Unlock()
+
+	var (
+		pending    []models.StatEvent
+		truth      map[statKey]models.StatDaily
+		quantities map[statKey]int
+	)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// События, закоммиченные до снимка, забираем вместе с ним, чтобы кэш соответствовал снимку
+		for {
+			events, err := claimEvents(tx, dispatchBatch)
+			if err != nil {
+				return err
+			}
+			pending = append(pending, events...)
+			if len(events) < dispatchBatch {
+				break
+			}
+		}
+
+		var err error
+		truth, quantities, err = loadTotals(tx)
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("processed_at < ?", time.Now().Add(-processedEventsTTL)).
+			Delete(&models.StatEvent{}).Error
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return 0, fmt.Errorf("failed to reconcile stats: %w", err)
+	}
+
+	for _, event := range pending {
+		applyEvent(event)
+	}
+
+	regionalStats.mu.Lock()
+	defer regionalStats.mu.Unlock()
+
+	keys := make(map[statKey]bool)
+	for key := range regionalStats.quantities {
+		keys[key] = true
+	}
+	for key := range quantities {
+		keys[key] = true
+	}
+
+	repaired := 0
+	for key := range keys {
+		if quantities[key] == regionalStats.quantities[key] &&
+			sameStat(truth[key], regionalStats.stats[key], quantities[key]) {
+			continue
+		}
+
+		log.Printf(
+			"stats drift for region %d on %s: cache has %d reports, DB has %d; repairing",
+			key.regionID,
+			key.date,
+			regionalStats.quantities[key],
+			quantities[key],
+		)
+		repaired++
+
+		if quantities[key] == 0 {
+			delete(regionalStats.stats, key)
+			delete(regionalStats.quantities, key)
+			continue
+		}
+		regionalStats.stats[key] = truth[key]
+		regionalStats.quantities[key] = quantities[key]
+	}
+
+	return repaired, nil
+}
+
+// Забрать необработанные события и пометить их обработанными в транзакции tx.
+// SKIP LOCKED не дает двум проходам забрать одно событие
+func claimEvents(tx *gorm.DB, limit int) ([]models.StatEvent, error) {
+	var events []models.StatEvent
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("processed_at IS NULL").
+		Order("id").
+		Limit(limit).
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+
+	if err := tx.Model(&models.StatEvent{}).
+		Where("id IN ?", ids).
+		Update("processed_at", time.Now()).Error; err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// Суммы и количество отчетов по региону и дате из БД
+func loadTotals(tx *gorm.DB) (map[statKey]models.StatDaily, map[statKey]int, error) {
+	var stats []models.StatDaily
+
+	if err := tx.Preload("Products").Preload("Metrics").Find(&stats).Error; err != nil {
+		return nil, nil, err
+	}
+
+	totals := make(map[statKey]models.StatDaily)
+	quantities := make(map[statKey]int)
+
+	for _, stat := range stats {
+		key := newKey(stat.RegionID, stat.Date)
+		totals[key] = addStat(totals[key], stat)
+		quantities[key]++
+	}
+
+	return totals, quantities, nil
+}
+
+func applyEvent(event models.StatEvent) {
+	if event.Kind == models.StatEventPurge {
+		cutoff, err := time.ParseInLocation("2006-01-02", normalizeDate(event.Date), time.Local)
+		if err != nil {
+			log.Printf("skipping stat event %d: invalid date %q", event.ID, event.Date)
+			return
+		}
+		ClearStatsOlderThan(event.RegionID, cutoff)
+		return
+	}
+
+	var oldStat, newStat models.StatDaily
+	if len(event.OldData) > 0 {
+		if err := json.Unmarshal(event.OldData, &oldStat); err != nil {
+			log.Printf("skipping stat event %d: %v", event.ID, err)
+			return
+		}
+	}
+	if len(event.NewData) > 0 {
+		if err := json.Unmarshal(event.NewData, &newStat); err != nil {
+			log.Printf("skipping stat event %d: %v", event.ID, err)
+			return
+		}
+	}
+
+	switch event.Kind {
+	case models.StatEventAdd:
+		AddStatForRegion(event.RegionID, newStat)
+	case models.StatEventUpdate:
+		UpdateStatForRegion(event.RegionID, oldStat, newStat)
+	case models.StatEventRemove:
+		RemoveStatForRegion(event.RegionID, oldStat)
+	default:
+		log.Printf("skipping stat event %d: unknown kind %q", event.ID, event.Kind)
+	}
+}
+
+// Совпадают ли агрегаты с учетом округления при накоплении (до 0.01 на отчет)
+func sameStat(a, b models.StatDaily, quantity int) bool {
+	tolerance := 0.01*float64(max(quantity, 1)) + 1e-9
+
+	codes := make(map[string]bool)
+	for _, p := range a.Products {
+		codes[p.Code] = true
+	}
+	for _, p := range b.Products {
+		codes[p.Code] = true
+	}
+	for code := range codes {
+		var pa, pb models.StatProduct
+		if p := a.Product(code); p != nil {
+			pa = *p
+		}
+		if p := b.Product(code); p != nil {
+			pb = *p
+		}
+		if math.Abs(pa.Plan-pb.Plan) > tolerance ||
+			math.Abs(pa.Fact-pb.Fact) > tolerance ||
+			math.Abs(pa.Dif-pb.Dif) > tolerance {
+			return false
+		}
+	}
+
+	codes = make(map[string]bool)
+	for _, m := range a.Metrics {
+		codes[m.Code] = true
+	}
+	for _, m := range b.Metrics {
+		codes[m.Code] = true
+	}
+	for code := range codes {
+		var va, vb int
+		if m := a.Metric(code); m != nil {
+			va = m.Value
+		}
+		if m := b.Metric(code); m != nil {
+			vb = m.Value
+		}
+		if va != vb {
+			return false
+		}
+	}
+
+	return true
+}
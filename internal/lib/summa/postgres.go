@@ -0,0 +1,163 @@
+package summa
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/models"
+	"gorm.io/gorm"
+)
+
+// Агрегатор, который считает суммы SQL-запросами при каждом обращении.
+// Состояния в памяти нет, поэтому все экземпляры приложения отвечают одинаково
+type postgresAggregator struct {
+	db *gorm.DB
+}
+
+type regionCount struct {
+	RegionID uint
+	Reports  int
+}
+
+type productSum struct {
+	RegionID  uint
+	ProductID uint
+	Code      string
+	Plan      float64
+	Fact      float64
+	Dif       float64
+}
+
+type metricSum struct {
+	RegionID uint
+	Code     string
+	Value    int
+}
+
+func (a *postgresAggregator) RegionStats(regionID uint, date string) (models.StatDaily, int, error) {
+	stats, quantities, err := a.aggregate(normalizeDate(date), regionID)
+	if err != nil {
+		return models.StatDaily{}, 0, err
+	}
+
+	return stats[regionID], quantities[regionID], nil
+}
+
+func (a *postgresAggregator) AllRegionStats(
+	date string,
+) (map[uint]models.StatDaily, map[uint]int, error) {
+	return a.aggregate(normalizeDate(date), 0)
+}
+
+func (a *postgresAggregator) TotalStats(date string) (models.StatDaily, int, error) {
+	stats, quantities, err := a.aggregate(normalizeDate(date), 0)
+	if err != nil {
+		return models.StatDaily{}, 0, err
+	}
+
+	var total models.StatDaily
+	totalQuantity := 0
+	for regionID, stat := range stats {
+		total = addStat(total, stat)
+		totalQuantity += quantities[regionID]
+	}
+
+	return total, totalQuantity, nil
+}
+
+// События outbox не нужны для подсчета, их достаточно пометить обработанными
+func (a *postgresAggregator) Dispatch() error {
+	return a.db.Model(&models.StatEvent{}).
+		Where("processed_at IS NULL").
+		Update("processed_at", time.Now()).Error
+}
+
+func (a *postgresAggregator) Rebuild() error {
+	return a.Dispatch()
+}
+
+// Агрегаты всегда читаются из БД и расходиться не могут, чистим только старые события
+func (a *postgresAggregator) Reconcile() (int, error) {
+	if err := a.Dispatch(); err != nil {
+		return 0, err
+	}
+
+	return 0, a.db.Where("processed_at < ?", time.Now().Add(-processedEventsTTL)).
+		Delete(&models.StatEvent{}).Error
+}
+
+// Суммы по регионам за дату; regionID 0 - все регионы
+func (a *postgresAggregator) aggregate(
+	date string,
+	regionID uint,
+) (map[uint]models.StatDaily, map[uint]int, error) {
+	filter := "d.date = ?"
+	args := []interface{}{date}
+	if regionID != 0 {
+		filter += " AND d.region_id = ?"
+		args = append(args, regionID)
+	}
+
+	var counts []regionCount
+	if err := a.db.Raw(`
+		SELECT d.region_id, COUNT(*) AS reports
+		FROM stat_dailies d
+		WHERE `+filter+`
+		GROUP BY d.region_id`, args...).
+		Scan(&counts).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to count reports: %w", err)
+	}
+
+	var products []productSum
+	if err := a.db.Raw(`
+		SELECT d.region_id, p.product_id, p.code,
+			ROUND(SUM(p.plan)::numeric, 2) AS plan,
+			ROUND(SUM(p.fact)::numeric, 2) AS fact,
+			ROUND(SUM(p.dif)::numeric, 2) AS dif
+		FROM stat_products p
+		JOIN stat_dailies d ON d.id = p.stat_daily_id
+		WHERE `+filter+`
+		GROUP BY d.region_id, p.product_id, p.code
+		ORDER BY p.code`, args...).
+		Scan(&products).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to sum products: %w", err)
+	}
+
+	var metrics []metricSum
+	if err := a.db.Raw(`
+		SELECT d.region_id, m.code, SUM(m.value) AS value
+		FROM stat_metrics m
+		JOIN stat_dailies d ON d.id = m.stat_daily_id
+		WHERE `+filter+`
+		GROUP BY d.region_id, m.code
+		ORDER BY m.code`, args...).
+		Scan(&metrics).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to sum metrics: %w", err)
+	}
+
+	stats := make(map[uint]models.StatDaily)
+	quantities := make(map[uint]int)
+
+	for _, c := range counts {
+		quantities[c.RegionID] = c.Reports
+		stats[c.RegionID] = models.StatDaily{Date: date, RegionID: c.RegionID}
+	}
+	for _, p := range products {
+		stat := stats[p.RegionID]
+		stat.Products = append(stat.Products, models.StatProduct{
+			ProductID: p.ProductID,
+			Code:      p.Code,
+			Plan:      p.Plan,
+			Fact:      p.Fact,
+			Dif:       p.Dif,
+		})
+		stats[p.RegionID] = stat
+	}
+	for _, m := range metrics {
+		stat := stats[m.RegionID]
+		stat.Metrics = append(stat.Metrics, models.StatMetric{Code: m.Code, Value: m.Value})
+		stats[m.RegionID] = stat
+	}
+
+	return stats, quantities, nil
+}
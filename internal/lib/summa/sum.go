@@ -4,21 +4,28 @@ import (
 	"fmt"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/Wladim1r/statcounter/internal/models"
 )
 
+// Ключ агрегата: регион и дата отчета
+type statKey struct {
+	regionID uint
+	date     string // YYYY-MM-DD
+}
+
 // Структура для хранения статистики по регионам
 type RegionalStats struct {
 	mu         sync.RWMutex
-	stats      map[uint]models.StatDaily // regionID -> aggregated stats
-	quantities map[uint]int              // regionID -> quantity of reports
+	stats      map[statKey]models.StatDaily // (regionID, date) -> aggregated stats
+	quantities map[statKey]int              // (regionID, date) -> quantity of reports
 }
 
 var (
 	regionalStats = &RegionalStats{
-		stats:      make(map[uint]models.StatDaily),
-		quantities: make(map[uint]int),
+		stats:      make(map[statKey]models.StatDaily),
+		quantities: make(map[statKey]int),
 	}
 )
 
@@ -26,51 +33,20 @@ func UpdateStatForRegion(regionID uint, oldStat, newStat models.StatDaily) {
 	regionalStats.mu.Lock()
 	defer regionalStats.mu.Unlock()
 
-	fmt.Printf("Updating stat for region %d: old=%+v, new=%+v\n", regionID, oldStat, newStat)
-
-	currentStat := regionalStats.stats[regionID]
+	fromKey := newKey(regionID, oldStat.Date)
+	toKey := newKey(regionID, newStat.Date)
 
 	// Вычитаем старые значения
-	currentStat.SeedPlan = roundFloat(currentStat.SeedPlan-oldStat.SeedPlan, 2)
-	currentStat.SeedFact = roundFloat(currentStat.SeedFact-oldStat.SeedFact, 2)
-	currentStat.SeedDif = roundFloat(currentStat.SeedDif-oldStat.SeedDif, 2)
-	currentStat.PumpkinPlan = roundFloat(currentStat.PumpkinPlan-oldStat.PumpkinPlan, 2)
-	currentStat.PumpkinFact = roundFloat(currentStat.PumpkinFact-oldStat.PumpkinFact, 2)
-	currentStat.PumpkinDif = roundFloat(currentStat.PumpkinDif-oldStat.PumpkinDif, 2)
-	currentStat.PeanutPlan = roundFloat(currentStat.PeanutPlan-oldStat.PeanutPlan, 2)
-	currentStat.PeanutFact = roundFloat(currentStat.PeanutFact-oldStat.PeanutFact, 2)
-	currentStat.PeanutDif = roundFloat(currentStat.PeanutDif-oldStat.PeanutDif, 2)
-	currentStat.AKB1 -= oldStat.AKB1
-	currentStat.AKB2 -= oldStat.AKB2
-	currentStat.NewTT -= oldStat.NewTT
-	currentStat.Mix -= oldStat.Mix
-	currentStat.NpOne -= oldStat.NpOne
-	currentStat.SetShel -= oldStat.SetShel
-	currentStat.DMP -= oldStat.DMP
-	currentStat.TopFive -= oldStat.TopFive
-	currentStat.News -= oldStat.News
+	regionalStats.stats[fromKey] = subStat(regionalStats.stats[fromKey], oldStat)
 
 	// Добавляем новые значения
-	currentStat.SeedPlan = roundFloat(currentStat.SeedPlan+newStat.SeedPlan, 2)
-	currentStat.SeedFact = roundFloat(currentStat.SeedFact+newStat.SeedFact, 2)
-	currentStat.SeedDif = roundFloat(currentStat.SeedDif+newStat.SeedDif, 2)
-	currentStat.PumpkinPlan = roundFloat(currentStat.PumpkinPlan+newStat.PumpkinPlan, 2)
-	currentStat.PumpkinFact = roundFloat(currentStat.PumpkinFact+newStat.PumpkinFact, 2)
-	currentStat.PumpkinDif = roundFloat(currentStat.PumpkinDif+newStat.PumpkinDif, 2)
-	currentStat.PeanutPlan = roundFloat(currentStat.PeanutPlan+newStat.PeanutPlan, 2)
-	currentStat.PeanutFact = roundFloat(currentStat.PeanutFact+newStat.PeanutFact, 2)
-	currentStat.PeanutDif = roundFloat(currentStat.PeanutDif+newStat.PeanutDif, 2)
-	currentStat.AKB1 += newStat.AKB1
-	currentStat.AKB2 += newStat.AKB2
-	currentStat.NewTT += newStat.NewTT
-	currentStat.Mix += newStat.Mix
-	currentStat.NpOne += newStat.NpOne
-	currentStat.SetShel += newStat.SetShel
-	currentStat.DMP += newStat.DMP
-	currentStat.TopFive += newStat.TopFive
-	currentStat.News += newStat.News
-
-	regionalStats.stats[regionID] = currentStat
+	regionalStats.stats[toKey] = addStat(regionalStats.stats[toKey], newStat)
+
+	// Если отчет перенесен на другую дату, переносим и его учет
+	if fromKey != toKey {
+		regionalStats.quantities[fromKey]--
+		regionalStats.quantities[toKey]++
+	}
 }
 
 // Добавить статистику для конкретного региона
@@ -78,75 +54,54 @@ func AddStatForRegion(regionID uint, stat models.StatDaily) {
 	regionalStats.mu.Lock()
 	defer regionalStats.mu.Unlock()
 
-	fmt.Printf("Adding stat for region %d: %+v\n", regionID, stat)
-
-	currentStat := regionalStats.stats[regionID]
-
-	// Суммируем статистику
-	currentStat.SeedPlan = roundFloat(currentStat.SeedPlan+stat.SeedPlan, 2)
-	currentStat.SeedFact = roundFloat(currentStat.SeedFact+stat.SeedFact, 2)
-	currentStat.SeedDif = roundFloat(currentStat.SeedDif+stat.SeedDif, 2)
-	currentStat.PumpkinPlan = roundFloat(currentStat.PumpkinPlan+stat.PumpkinPlan, 2)
-	currentStat.PumpkinFact = roundFloat(currentStat.PumpkinFact+stat.PumpkinFact, 2)
-	currentStat.PumpkinDif = roundFloat(currentStat.PumpkinDif+stat.PumpkinDif, 2)
-	currentStat.PeanutPlan = roundFloat(currentStat.PeanutPlan+stat.PeanutPlan, 2)
-	currentStat.PeanutFact = roundFloat(currentStat.PeanutFact+stat.PeanutFact, 2)
-	currentStat.PeanutDif = roundFloat(currentStat.PeanutDif+stat.PeanutDif, 2)
-	currentStat.AKB1 += stat.AKB1
-	currentStat.AKB2 += stat.AKB2
-	currentStat.NewTT += stat.NewTT
-	currentStat.Mix += stat.Mix
-	currentStat.NpOne += stat.NpOne
-	currentStat.SetShel += stat.SetShel
-	currentStat.DMP += stat.DMP
-	currentStat.TopFive += stat.TopFive
-	currentStat.News += stat.News
-
-	regionalStats.stats[regionID] = currentStat
-	regionalStats.quantities[regionID]++
-}
-
-// Получить агрегированную статистику для региона
-func GetStatsForRegion(regionID uint) (models.StatDaily, int) {
+	key := newKey(regionID, stat.Date)
+
+	regionalStats.stats[key] = addStat(regionalStats.stats[key], stat)
+	regionalStats.quantities[key]++
+}
+
+// Убрать удаленный отчет из статистики региона
+func RemoveStatForRegion(regionID uint, stat models.StatDaily) {
+	regionalStats.mu.Lock()
+	defer regionalStats.mu.Unlock()
+
+	key := newKey(regionID, stat.Date)
+
+	regionalStats.stats[key] = subStat(regionalStats.stats[key], stat)
+	regionalStats.quantities[key]--
+}
+
+// Получить агрегированную статистику для региона за дату
+func GetStatsForRegion(regionID uint, date string) (models.StatDaily, int) {
 	regionalStats.mu.RLock()
 	defer regionalStats.mu.RUnlock()
 
-	stat := regionalStats.stats[regionID]
-	quantity := regionalStats.quantities[regionID]
+	key := newKey(regionID, date)
+
+	stat := regionalStats.stats[key]
+	quantity := regionalStats.quantities[key]
 	return stat, quantity
 }
 
-// Получить общую статистику по всем регионам
-func GetTotalStats() (models.StatDaily, int) {
+// Получить общую статистику по всем регионам за дату
+func GetTotalStats(date string) (models.StatDaily, int) {
 	regionalStats.mu.RLock()
 	defer regionalStats.mu.RUnlock()
 
 	var totalStat models.StatDaily
 	totalQuantity := 0
+	date = normalizeDate(date)
 
-	for _, stat := range regionalStats.stats {
-		totalStat.SeedPlan = roundFloat(totalStat.SeedPlan+stat.SeedPlan, 2)
-		totalStat.SeedFact = roundFloat(totalStat.SeedFact+stat.SeedFact, 2)
-		totalStat.SeedDif = roundFloat(totalStat.SeedDif+stat.SeedDif, 2)
-		totalStat.PumpkinPlan = roundFloat(totalStat.PumpkinPlan+stat.PumpkinPlan, 2)
-		totalStat.PumpkinFact = roundFloat(totalStat.PumpkinFact+stat.PumpkinFact, 2)
-		totalStat.PumpkinDif = roundFloat(totalStat.PumpkinDif+stat.PumpkinDif, 2)
-		totalStat.PeanutPlan = roundFloat(totalStat.PeanutPlan+stat.PeanutPlan, 2)
-		totalStat.PeanutFact = roundFloat(totalStat.PeanutFact+stat.PeanutFact, 2)
-		totalStat.PeanutDif = roundFloat(totalStat.PeanutDif+stat.PeanutDif, 2)
-		totalStat.AKB1 += stat.AKB1
-		totalStat.AKB2 += stat.AKB2
-		totalStat.NewTT += stat.NewTT
-		totalStat.Mix += stat.Mix
-		totalStat.NpOne += stat.NpOne
-		totalStat.SetShel += stat.SetShel
-		totalStat.DMP += stat.DMP
-		totalStat.TopFive += stat.TopFive
-		totalStat.News += stat.News
-	}
-
-	for _, quantity := range regionalStats.quantities {
-		totalQuantity += quantity
+	for key, stat := range regionalStats.stats {
+		if key.date == date {
+			totalStat = addStat(totalStat, stat)
+		}
+	}
+
+	for key, quantity := range regionalStats.quantities {
+		if key.date == date {
+			totalQuantity += quantity
+		}
 	}
 
 	return totalStat, totalQuantity
@@ -157,50 +112,166 @@ func ClearStatsForRegion(regionID uint) {
 	regionalStats.mu.Lock()
 	defer regionalStats.mu.Unlock()
 
-	delete(regionalStats.stats, regionID)
-	delete(regionalStats.quantities, regionID)
+	for key := range regionalStats.stats {
+		if key.regionID == regionID {
+			delete(regionalStats.stats, key)
+		}
+	}
+	for key := range regionalStats.quantities {
+		if key.regionID == regionID {
+			delete(regionalStats.quantities, key)
+		}
+	}
 }
 
-// Очистить всю статистику
-func ClearAllStats() {
+// Очистить статистику региона за даты раньше cutoffDate
+func ClearStatsOlderThan(regionID uint, cutoffDate time.Time) {
 	regionalStats.mu.Lock()
 	defer regionalStats.mu.Unlock()
 
-	regionalStats.stats = make(map[uint]models.StatDaily)
-	regionalStats.quantities = make(map[uint]int)
+	cutoff := cutoffDate.Format("2006-01-02")
+
+	for key := range regionalStats.stats {
+		if key.regionID == regionID && key.date < cutoff {
+			delete(regionalStats.stats, key)
+		}
+	}
+	for key := range regionalStats.quantities {
+		if key.regionID == regionID && key.date < cutoff {
+			delete(regionalStats.quantities, key)
+		}
+	}
 }
 
-// Инициализировать статистику при запуске приложения
-func InitializeFromDB(db interface{}) error {
-	// Здесь можно добавить логику загрузки существующих данных из БД
-	// при старте приложения, если это необходимо
-	return nil
+// Очистить всю статистику
+func ClearAllStats() {
+	regionalStats.mu.Lock()
+	defer regionalStats.mu.Unlock()
+
+	regionalStats.stats = make(map[statKey]models.StatDaily)
+	regionalStats.quantities = make(map[statKey]int)
 }
 
-// Получить список всех регионов со статистикой
-func GetAllRegionalStats() map[uint]models.StatDaily {
+// Получить статистику всех регионов за дату
+func GetAllRegionalStats(date string) map[uint]models.StatDaily {
 	regionalStats.mu.RLock()
 	defer regionalStats.mu.RUnlock()
 
+	date = normalizeDate(date)
+
 	result := make(map[uint]models.StatDaily)
-	for regionID, stat := range regionalStats.stats {
-		result[regionID] = stat
+	for key, stat := range regionalStats.stats {
+		if key.date == date {
+			result[key.regionID] = stat
+		}
 	}
 	return result
 }
 
-// Получить количество отчетов для всех регионов
-func GetAllQuantities() map[uint]int {
+// Получить количество отчетов для всех регионов за дату
+func GetAllQuantities(date string) map[uint]int {
 	regionalStats.mu.RLock()
 	defer regionalStats.mu.RUnlock()
 
+	date = normalizeDate(date)
+
 	result := make(map[uint]int)
-	for regionID, quantity := range regionalStats.quantities {
-		result[regionID] = quantity
+	for key, quantity := range regionalStats.quantities {
+		if key.date == date {
+			result[key.regionID] = quantity
+		}
 	}
 	return result
 }
 
+func newKey(regionID uint, date string) statKey {
+	return statKey{regionID: regionID, date: normalizeDate(date)}
+}
+
+// Привести дату к виду YYYY-MM-DD (из БД дата может прийти как 2006-01-02T00:00:00Z)
+func normalizeDate(date string) string {
+	if len(date) > len("2006-01-02") {
+		return date[:len("2006-01-02")]
+	}
+	return date
+}
+
+// Сложить отчеты в один агрегат (по тем же правилам, что и региональная статистика)
+func Sum(stats ...models.StatDaily) models.StatDaily {
+	var total models.StatDaily
+	for _, stat := range stats {
+		total = addStat(total, stat)
+	}
+	return total
+}
+
+// Сложить два агрегата
+func addStat(currentStat, stat models.StatDaily) models.StatDaily {
+	currentStat.Products = cloneProducts(currentStat.Products)
+
+	for _, p := range stat.Products {
+		current := currentStat.Product(p.Code)
+		if current == nil {
+			currentStat.Products = append(currentStat.Products, models.StatProduct{
+				ProductID: p.ProductID,
+				Code:      p.Code,
+			})
+			current = &currentStat.Products[len(currentStat.Products)-1]
+		}
+
+		current.Plan = roundFloat(current.Plan+p.Plan, 2)
+		current.Fact = roundFloat(current.Fact+p.Fact, 2)
+		current.Dif = roundFloat(current.Dif+p.Dif, 2)
+	}
+
+	currentStat.Metrics = cloneMetrics(currentStat.Metrics)
+
+	for _, m := range stat.Metrics {
+		current := currentStat.Metric(m.Code)
+		if current == nil {
+			currentStat.Metrics = append(currentStat.Metrics, models.StatMetric{Code: m.Code})
+			current = &currentStat.Metrics[len(currentStat.Metrics)-1]
+		}
+
+		current.Value += m.Value
+	}
+
+	return currentStat
+}
+
+// Вычесть один агрегат из другого
+func subStat(currentStat, stat models.StatDaily) models.StatDaily {
+	negative := stat
+	negative.Products = cloneProducts(stat.Products)
+	for i := range negative.Products {
+		negative.Products[i].Plan = -negative.Products[i].Plan
+		negative.Products[i].Fact = -negative.Products[i].Fact
+		negative.Products[i].Dif = -negative.Products[i].Dif
+	}
+
+	negative.Metrics = cloneMetrics(stat.Metrics)
+	for i := range negative.Metrics {
+		negative.Metrics[i].Value = -negative.Metrics[i].Value
+	}
+
+	return addStat(currentStat, negative)
+}
+
+// Агрегаты хранятся в map по значению, поэтому срезы копируются перед изменением
+func cloneProducts(products []models.StatProduct) []models.StatProduct {
+	if products == nil {
+		return nil
+	}
+	return append([]models.StatProduct(nil), products...)
+}
+
+func cloneMetrics(metrics []models.StatMetric) []models.StatMetric {
+	if metrics == nil {
+		return nil
+	}
+	return append([]models.StatMetric(nil), metrics...)
+}
+
 func roundFloat(val float64, precision int) float64 {
 	formatted := fmt.Sprintf("%.*f", precision, val)
 	result, _ := strconv.ParseFloat(formatted, 64)
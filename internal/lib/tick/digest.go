@@ -0,0 +1,84 @@
+package tick
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/service"
+	"github.com/Wladim1r/statcounter/internal/lib/schedule"
+	"github.com/Wladim1r/statcounter/internal/models"
+)
+
+// Как часто проверять, не наступило ли время сводки
+const digestCheckInterval = time.Minute
+
+// Отправлять ежедневные сводки регионов в рабочие дни, когда наступает время из расписания.
+// Повторная отправка после перезапуска исключается журналом уведомлений
+func SendDigestsWithContext(
+	ctx context.Context,
+	digests service.DigestService,
+	calendar service.CalendarService,
+	times *schedule.Times,
+	regions service.RegionLister,
+) {
+	// Последняя дата, за которую сводка региона уже обработана
+	done := make(map[uint]string)
+
+	sendDue := func(now time.Time) {
+		today := now.Format("2006-01-02")
+		// Регионы читаются на каждой проверке, чтобы учитывать созданные после запуска
+		list, err := regions.GetRegions()
+		if err != nil {
+			log.Printf("error loading regions for digests: %v", err)
+			return
+		}
+		for _, regionID := range times.RegionIDs(list) {
+			clock, _ := times.At(regionID)
+			if done[regionID] == today || now.Before(clock.On(now)) {
+				continue
+			}
+
+			working, err := calendar.IsWorkingDay(regionID, now)
+			if err != nil {
+				log.Printf("error checking working day for region %d digest: %v", regionID, err)
+				continue
+			}
+			done[regionID] = today
+			if !working {
+				continue
+			}
+
+			result, err := digests.SendDigest(ctx, regionID, today, false)
+			if err != nil {
+				log.Printf("error sending digest for region %d: %v", regionID, err)
+				continue
+			}
+
+			var sent, failed int
+			for _, delivery := range result.Deliveries {
+				switch delivery.Status {
+				case models.NotificationSent:
+					sent++
+				case models.NotificationFailed:
+					failed++
+				}
+			}
+			log.Printf("digest for region %d on %s: %d delivered, %d failed", regionID, today, sent, failed)
+		}
+	}
+
+	ticker := time.NewTicker(digestCheckInterval)
+	defer ticker.Stop()
+
+	sendDue(time.Now())
+	for {
+		select {
+		case now := <-ticker.C:
+			sendDue(now)
+		case <-ctx.Done():
+			log.Println("digest goroutine cancelled")
+			return
+		}
+	}
+}
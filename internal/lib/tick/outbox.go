@@ -0,0 +1,66 @@
+package tick
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/repository"
+)
+
+const (
+	// Как часто подбирать события outbox, не примененные сразу после записи
+	dispatchInterval = 10 * time.Second
+	// Период сверки summa с БД, если STATS_RECONCILE_INTERVAL не задан
+	defaultReconcileInterval = time.Hour
+)
+
+// Период сверки агрегатов из STATS_RECONCILE_INTERVAL (Go duration, например 30m)
+func ReconcileIntervalFromEnv() (time.Duration, error) {
+	value := strings.TrimSpace(os.Getenv("STATS_RECONCILE_INTERVAL"))
+	if value == "" {
+		return defaultReconcileInterval, nil
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < time.Minute {
+		return 0, fmt.Errorf("invalid STATS_RECONCILE_INTERVAL %q: expected duration of at least 1m", value)
+	}
+
+	return interval, nil
+}
+
+// Фоново применять события outbox к summa и периодически сверять агрегаты с БД
+func SyncStatsWithContext(
+	ctx context.Context,
+	repo repository.DjnRepo,
+	reconcileInterval time.Duration,
+) {
+	dispatchTicker := time.NewTicker(dispatchInterval)
+	defer dispatchTicker.Stop()
+
+	reconcileTicker := time.NewTicker(reconcileInterval)
+	defer reconcileTicker.Stop()
+
+	for {
+		select {
+		case <-dispatchTicker.C:
+			if err := repo.DispatchStatEvents(); err != nil {
+				log.Printf("error dispatching stat events: %v", err)
+			}
+		case <-reconcileTicker.C:
+			repaired, err := repo.ReconcileStats()
+			if err != nil {
+				log.Printf("error reconciling stats: %v", err)
+			} else if repaired > 0 {
+				log.Printf("stats reconciliation repaired %d region-date aggregates", repaired)
+			}
+		case <-ctx.Done():
+			log.Println("stats sync goroutine cancelled")
+			return
+		}
+	}
+}
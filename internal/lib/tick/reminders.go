@@ -0,0 +1,100 @@
+package tick
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Wladim1r/statcounter/internal/api/service"
+	"github.com/Wladim1r/statcounter/internal/lib/schedule"
+)
+
+const (
+	// Как часто проверять сроки сдачи отчетов
+	reminderCheckInterval = time.Minute
+	// За сколько до срока напоминать, если REMINDER_BEFORE не задан
+	defaultRemindBefore = time.Hour
+)
+
+// За сколько до срока сдачи отчета напоминать представителям (REMINDER_BEFORE, Go duration)
+func RemindBeforeFromEnv() (time.Duration, error) {
+	value := strings.TrimSpace(os.Getenv("REMINDER_BEFORE"))
+	if value == "" {
+		return defaultRemindBefore, nil
+	}
+
+	before, err := time.ParseDuration(value)
+	if err != nil || before < 0 || before >= 24*time.Hour {
+		return 0, fmt.Errorf("invalid REMINDER_BEFORE %q: expected duration from 0 to 24h", value)
+	}
+
+	return before, nil
+}
+
+// Напоминать о несданных отчетах до срока и сообщать администраторам после него.
+// Повторные уведомления за тот же день исключаются журналом уведомлений
+func SendRemindersWithContext(
+	ctx context.Context,
+	missing service.MissingService,
+	deadlines *schedule.Times,
+	regions service.RegionLister,
+) {
+	// Дата, за которую регион уже прошел эскалацию - дальше проверять до конца дня нечего
+	done := make(map[uint]string)
+	// Последний этап по региону, чтобы писать в лог только смену этапа
+	stages := make(map[uint]string)
+
+	check := func(now time.Time) {
+		today := now.Format("2006-01-02")
+		// Регионы читаются на каждой проверке, чтобы учитывать созданные после запуска
+		list, err := regions.GetRegions()
+		if err != nil {
+			log.Printf("error loading regions for report reminders: %v", err)
+			return
+		}
+		for _, regionID := range deadlines.RegionIDs(list) {
+			if done[regionID] == today {
+				continue
+			}
+
+			result, err := missing.SendReminders(ctx, regionID, now)
+			if err != nil {
+				log.Printf("error sending report reminders for region %d: %v", regionID, err)
+				continue
+			}
+
+			deadline, _ := deadlines.At(regionID)
+			if !now.Before(deadline.On(now)) {
+				done[regionID] = today
+			}
+			stage := today + " " + result.Stage
+			if result.Stage != service.ReminderStageNone && stages[regionID] != stage {
+				stages[regionID] = stage
+				log.Printf(
+					"report %s for region %d on %s: %d notifications",
+					result.Stage,
+					regionID,
+					today,
+					len(result.Deliveries),
+				)
+			}
+		}
+	}
+
+	ticker := time.NewTicker(reminderCheckInterval)
+	defer ticker.Stop()
+
+	check(time.Now())
+	for {
+		select {
+		case now := <-ticker.C:
+			check(now)
+		case <-ctx.Done():
+			log.Println("reminder goroutine cancelled")
+			return
+		}
+	}
+}
@@ -6,16 +6,36 @@ import (
 	"time"
 
 	"github.com/Wladim1r/statcounter/internal/api/repository"
+	"github.com/Wladim1r/statcounter/internal/api/service"
+	"github.com/Wladim1r/statcounter/internal/lib/retention"
 )
 
-func TruncateToTickerMonthlyWithContext(ctx context.Context, repo repository.DjnRepo) {
-	// Функция для удаления старых данных
+func TruncateToTickerMonthlyWithContext(
+	ctx context.Context,
+	repo repository.DjnRepo,
+	policy *retention.Policy,
+	regions service.RegionLister,
+) {
+	// Функция для удаления старых данных согласно сроку хранения каждого региона.
+	// Список регионов читается при каждом запуске, чтобы очищать и новые регионы
 	deleteOldData := func() {
-		cutoffDate := time.Now().AddDate(0, 0, -3)
-		if err := repo.DeleteOlderThan(cutoffDate); err != nil {
-			log.Printf("error deleting old data: %v", err)
-		} else {
-			log.Printf("successfully deleted data older than %v", cutoffDate.Format("2006-01-02"))
+		now := time.Now()
+		list, err := regions.GetRegions()
+		if err != nil {
+			log.Printf("error loading regions for cleanup: %v", err)
+			return
+		}
+		for _, region := range list {
+			cutoffDate := policy.Cutoff(region.ID, now)
+			if err := repo.DeleteOlderThan(region.ID, cutoffDate); err != nil {
+				log.Printf("error deleting old data for region %d: %v", region.ID, err)
+			} else {
+				log.Printf(
+					"successfully deleted data older than %v for region %d",
+					cutoffDate.Format("2006-01-02"),
+					region.ID,
+				)
+			}
 		}
 	}
 
@@ -0,0 +1,90 @@
+package models
+
+import "encoding/json"
+
+// Месячная сводка отчетов, сохраняемая перед удалением ежедневных записей.
+// Пустой Name - сводка по региону целиком
+type MonthlySummary struct {
+	ID       uint   `json:"-"         gorm:"primarykey"`
+	Month    string `json:"month"     gorm:"type:date;not null;uniqueIndex:idx_monthly_summary"`
+	RegionID uint   `json:"region_id" gorm:"not null;uniqueIndex:idx_monthly_summary"`
+	Name     string `json:"name"      gorm:"not null;default:'';uniqueIndex:idx_monthly_summary"`
+	Reports  int    `json:"reports"   gorm:"not null;default:0"`
+
+	Products []MonthlySummaryProduct `json:"-" gorm:"foreignKey:SummaryID;constraint:OnDelete:CASCADE"`
+	Metrics  []MonthlySummaryMetric  `json:"-" gorm:"foreignKey:SummaryID;constraint:OnDelete:CASCADE"`
+}
+
+// Суммы плана, факта и разницы по продукту за месяц
+type MonthlySummaryProduct struct {
+	ID        uint    `gorm:"primarykey"`
+	SummaryID uint    `gorm:"not null;uniqueIndex:idx_monthly_summary_product"`
+	Code      string  `gorm:"not null;uniqueIndex:idx_monthly_summary_product"`
+	Plan      float64 `gorm:"not null;default:0"`
+	Fact      float64 `gorm:"not null;default:0"`
+	Dif       float64 `gorm:"not null;default:0"`
+}
+
+// Сумма значений счетчика за месяц
+type MonthlySummaryMetric struct {
+	ID        uint   `gorm:"primarykey"`
+	SummaryID uint   `gorm:"not null;uniqueIndex:idx_monthly_summary_metric"`
+	Code      string `gorm:"not null;uniqueIndex:idx_monthly_summary_metric"`
+	Value     int    `gorm:"not null;default:0"`
+}
+
+// Представить сводку как агрегированный отчет, чтобы складывать ее с ежедневными записями
+func (m *MonthlySummary) Stat() StatDaily {
+	stat := StatDaily{
+		Date:     m.Month,
+		Name:     m.Name,
+		RegionID: m.RegionID,
+	}
+	for _, p := range m.Products {
+		stat.Products = append(stat.Products, StatProduct{Code: p.Code, Plan: p.Plan, Fact: p.Fact, Dif: p.Dif})
+	}
+	for _, metric := range m.Metrics {
+		stat.Metrics = append(stat.Metrics, StatMetric{Code: metric.Code, Value: metric.Value})
+	}
+	return stat
+}
+
+// Заменить суммы сводки значениями агрегированного отчета
+func (m *MonthlySummary) SetStat(stat StatDaily) {
+	m.Products = nil
+	for _, p := range stat.Products {
+		m.Products = append(m.Products, MonthlySummaryProduct{
+			SummaryID: m.ID,
+			Code:      p.Code,
+			Plan:      p.Plan,
+			Fact:      p.Fact,
+			Dif:       p.Dif,
+		})
+	}
+
+	m.Metrics = nil
+	for _, metric := range stat.Metrics {
+		m.Metrics = append(m.Metrics, MonthlySummaryMetric{
+			SummaryID: m.ID,
+			Code:      metric.Code,
+			Value:     metric.Value,
+		})
+	}
+}
+
+// MarshalJSON разворачивает суммы сводки в те же плоские поля, что и у ежедневного отчета
+func (m MonthlySummary) MarshalJSON() ([]byte, error) {
+	type monthlySummary MonthlySummary
+
+	if len(m.Month) > len("2006-01") {
+		m.Month = m.Month[:len("2006-01")]
+	}
+
+	data, err := json.Marshal(monthlySummary(m))
+	if err != nil {
+		return nil, err
+	}
+
+	stat := m.Stat()
+	return flatten(data, stat.Products, stat.Metrics)
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Виды правил премирования
+const (
+	// Ставка по ступеням выполнения плана продукта
+	BonusRulePlan = "plan"
+	// Фиксированная сумма за единицу KPI-счетчика
+	BonusRuleMetric = "metric"
+)
+
+// Статусы расчета премий
+const (
+	BonusRunDraft  = "draft"
+	BonusRunLocked = "locked"
+)
+
+// Ступень правила: при выполнении плана от From процентов начисляется Rate процентов
+// от базы и фиксированная сумма Amount. Действует ступень с наибольшим From
+type BonusTier struct {
+	From   float64 `json:"from"`
+	Rate   float64 `json:"rate"`
+	Amount float64 `json:"amount,omitempty"`
+}
+
+// Правило премирования. RegionID 0 - правило для всех регионов, правило региона
+// того же вида и кода заменяет общее
+type BonusRule struct {
+	ID       uint   `json:"id"        gorm:"primarykey"`
+	RegionID uint   `json:"region_id" gorm:"not null;default:0;index"`
+	Name     string `json:"name"      gorm:"not null"`
+	Kind     string `json:"kind"      gorm:"not null"`
+	// Код продукта (plan) или счетчика (metric)
+	Code string `json:"code" gorm:"not null"`
+
+	// База для процента ступени (например, оклад). Без базы процент берется от факта
+	Base  *float64    `json:"base,omitempty"`
+	Tiers []BonusTier `json:"tiers,omitempty" gorm:"serializer:json;type:jsonb"`
+	// Сумма за единицу счетчика
+	PerUnit float64 `json:"per_unit,omitempty"`
+
+	Active    bool      `json:"active"     gorm:"not null"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Расчет премий региона за месяц. Утвержденный (locked) расчет не пересчитывается
+type BonusRun struct {
+	ID       uint   `json:"id"        gorm:"primarykey"`
+	Month    string `json:"month"     gorm:"type:date;not null;uniqueIndex:idx_bonus_run"`
+	RegionID uint   `json:"region_id" gorm:"not null;uniqueIndex:idx_bonus_run"`
+	Status   string `json:"status"    gorm:"not null;default:'draft'"`
+	// Правила, по которым выполнен расчет
+	Rules []BonusRule `json:"rules" gorm:"serializer:json;type:jsonb"`
+	Total float64     `json:"total"`
+
+	Payouts []BonusPayout `json:"payouts,omitempty" gorm:"foreignKey:RunID;constraint:OnDelete:CASCADE"`
+
+	CalculatedBy string     `json:"calculated_by"`
+	CalculatedAt time.Time  `json:"calculated_at"`
+	LockedBy     string     `json:"locked_by,omitempty"`
+	LockedAt     *time.Time `json:"locked_at,omitempty"`
+}
+
+// MarshalJSON отдает месяц расчета в виде YYYY-MM
+func (r BonusRun) MarshalJSON() ([]byte, error) {
+	type bonusRun BonusRun
+
+	run := bonusRun(r)
+	if len(run.Month) >= len("2006-01") {
+		run.Month = run.Month[:len("2006-01")]
+	}
+
+	return json.Marshal(run)
+}
+
+// Премия пользователя в расчете
+type BonusPayout struct {
+	ID      uint        `json:"-"       gorm:"primarykey"`
+	RunID   uint        `json:"-"       gorm:"not null;uniqueIndex:idx_bonus_payout"`
+	Name    string      `json:"name"    gorm:"not null;uniqueIndex:idx_bonus_payout"`
+	Reports int         `json:"reports"`
+	Total   float64     `json:"total"`
+	Lines   []BonusLine `json:"lines"   gorm:"serializer:json;type:jsonb"`
+}
+
+// Начисление по одному правилу
+type BonusLine struct {
+	Rule string `json:"rule"`
+	Kind string `json:"kind"`
+	Code string `json:"code"`
+	// Для plan: план, факт и процент выполнения за месяц
+	Plan        float64  `json:"plan,omitempty"`
+	Fact        float64  `json:"fact,omitempty"`
+	Achievement *float64 `json:"achievement,omitempty"`
+	Rate        float64  `json:"rate,omitempty"`
+	// Для metric: значение счетчика за месяц
+	Value  int     `json:"value,omitempty"`
+	Amount float64 `json:"amount"`
+}
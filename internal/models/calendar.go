@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Выходные дни недели региона (ISO: 1 - понедельник ... 7 - воскресенье).
+// RegionID 0 - настройка по умолчанию для всех регионов
+type WorkWeek struct {
+	ID        uint      `json:"id"         gorm:"primarykey"`
+	RegionID  uint      `json:"region_id"  gorm:"not null;uniqueIndex"`
+	Weekends  []int     `json:"weekends"   gorm:"serializer:json;type:jsonb;not null"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Особый день календаря: праздник или выходной (Working=false) либо рабочий день,
+// перенесенный на выходной (Working=true). RegionID 0 - день для всех регионов,
+// день региона имеет приоритет над общим
+type CalendarDay struct {
+	ID        uint      `json:"id"         gorm:"primarykey"`
+	RegionID  uint      `json:"region_id"  gorm:"not null;uniqueIndex:idx_calendar_day"`
+	Date      string    `json:"date"       gorm:"type:date;not null;uniqueIndex:idx_calendar_day"`
+	Working   bool      `json:"working"    gorm:"not null;default:false"`
+	Name      string    `json:"name"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
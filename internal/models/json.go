@@ -0,0 +1,188 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	planSuffix = "_plan"
+	factSuffix = "_fact"
+	difSuffix  = "_dif"
+	// Источник плана и заявленный представителем план, если его заменила месячная цель
+	planSourceSuffix  = "_plan_source"
+	claimedPlanSuffix = "_plan_claimed"
+)
+
+// Поля StatDaily, которые сериализуются как есть и не могут быть кодами счетчиков
+var statDailyFields = jsonFieldNames(reflect.TypeOf(StatDaily{}))
+
+// MarshalJSON разворачивает продукты отчета в плоские поля <code>_plan, <code>_fact, <code>_dif,
+// а счетчики - в поля <code>
+func (s StatDaily) MarshalJSON() ([]byte, error) {
+	type statDaily StatDaily
+
+	data, err := json.Marshal(statDaily(s))
+	if err != nil {
+		return nil, err
+	}
+
+	return flatten(data, s.Products, s.Metrics)
+}
+
+// UnmarshalJSON собирает продукты отчета из плоских полей <code>_plan и <code>_fact,
+// а счетчики - из остальных числовых полей. Поля <code>_dif игнорируются,
+// разница всегда считается на сервере
+func (s *StatDaily) UnmarshalJSON(data []byte) error {
+	type statDaily StatDaily
+
+	var stat statDaily
+	if err := json.Unmarshal(data, &stat); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	products := make(map[string]*StatProduct)
+	product := func(code string) *StatProduct {
+		if p, ok := products[code]; ok {
+			return p
+		}
+		p := &StatProduct{Code: code}
+		products[code] = p
+		return p
+	}
+
+	metrics := make(map[string]int)
+
+	for key, raw := range fields {
+		switch {
+		case statDailyFields[key]:
+		case strings.HasSuffix(key, planSourceSuffix):
+			if err := json.Unmarshal(raw, &product(strings.TrimSuffix(key, planSourceSuffix)).PlanSource); err != nil {
+				return err
+			}
+		case strings.HasSuffix(key, claimedPlanSuffix):
+			if err := json.Unmarshal(raw, &product(strings.TrimSuffix(key, claimedPlanSuffix)).ClaimedPlan); err != nil {
+				return err
+			}
+		case strings.HasSuffix(key, planSuffix):
+			if err := json.Unmarshal(raw, &product(strings.TrimSuffix(key, planSuffix)).Plan); err != nil {
+				return err
+			}
+		case strings.HasSuffix(key, factSuffix):
+			if err := json.Unmarshal(raw, &product(strings.TrimSuffix(key, factSuffix)).Fact); err != nil {
+				return err
+			}
+		case strings.HasSuffix(key, difSuffix):
+		default:
+			var value float64
+			if err := json.Unmarshal(raw, &value); err != nil {
+				// Нечисловые поля не являются счетчиками
+				continue
+			}
+			if value != math.Trunc(value) {
+				return fmt.Errorf("metric %s must be an integer", key)
+			}
+			metrics[key] = int(value)
+		}
+	}
+
+	stat.Products = nil
+	for _, code := range sortedKeys(products) {
+		stat.Products = append(stat.Products, *products[code])
+	}
+
+	stat.Metrics = nil
+	for _, code := range sortedKeys(metrics) {
+		stat.Metrics = append(stat.Metrics, StatMetric{Code: code, Value: metrics[code]})
+	}
+
+	*s = StatDaily(stat)
+	return nil
+}
+
+// Добавить к JSON-объекту data плоские поля продуктов и счетчиков
+func flatten(data []byte, products []StatProduct, metrics []StatMetric) ([]byte, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	for _, p := range products {
+		for suffix, value := range map[string]float64{
+			planSuffix: p.Plan,
+			factSuffix: p.Fact,
+			difSuffix:  p.Dif,
+		} {
+			raw, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			fields[p.Code+suffix] = raw
+		}
+
+		// У агрегатов источник плана не заполнен
+		if p.PlanSource != "" {
+			raw, err := json.Marshal(p.PlanSource)
+			if err != nil {
+				return nil, err
+			}
+			fields[p.Code+planSourceSuffix] = raw
+		}
+		if p.ClaimedPlan != nil {
+			raw, err := json.Marshal(*p.ClaimedPlan)
+			if err != nil {
+				return nil, err
+			}
+			fields[p.Code+claimedPlanSuffix] = raw
+		}
+	}
+
+	for _, m := range metrics {
+		raw, err := json.Marshal(m.Value)
+		if err != nil {
+			return nil, err
+		}
+		fields[m.Code] = raw
+	}
+
+	return json.Marshal(fields)
+}
+
+// Проверить, что код счетчика не пересекается с полями отчета и суффиксами продуктов
+func IsReservedMetricCode(code string) bool {
+	return statDailyFields[code] ||
+		strings.HasSuffix(code, planSuffix) ||
+		strings.HasSuffix(code, factSuffix) ||
+		strings.HasSuffix(code, difSuffix) ||
+		strings.HasSuffix(code, planSourceSuffix) ||
+		strings.HasSuffix(code, claimedPlanSuffix)
+}
+
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,5 +1,11 @@
 package models
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 type StatDaily struct {
 	ID   uint   `json:"id,omitempty" gorm:"primarykey"`
 	Date string `json:"date"         gorm:"type:date;not null;uniqueIndex:idx_unique_daily_stat"`
@@ -7,29 +13,130 @@ type StatDaily struct {
 	Name     string `json:"name"      gorm:"uniqueIndex:idx_unique_daily_stat"`
 	RegionID uint   `json:"region_id" gorm:"uniqueIndex:idx_unique_daily_stat"`
 
-	SeedPlan float64 `json:"seed_plan"`
-	SeedFact float64 `json:"seed_fact"`
-	SeedDif  float64 `json:"seed_dif,omitempty"`
+	// План/факт по продуктам каталога, в JSON разворачиваются в поля <code>_plan, <code>_fact, <code>_dif
+	Products []StatProduct `json:"-" gorm:"foreignKey:StatDailyID;constraint:OnDelete:CASCADE"`
+
+	// Значения KPI-счетчиков, в JSON разворачиваются в поля <code>
+	Metrics []StatMetric `json:"-" gorm:"foreignKey:StatDailyID;constraint:OnDelete:CASCADE"`
+
+	// Увеличивается при каждом изменении, входит в ETag отчета
+	Version int `json:"version" gorm:"not null;default:1"`
+
+	// Кто и когда создал и последним изменил отчет (сам пользователь или администратор)
+	CreatedBy string    `json:"created_by,omitempty"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 
-	PumpkinPlan float64 `json:"pumpkin_plan"`
-	PumpkinFact float64 `json:"pumpkin_fact"`
-	PumpkinDif  float64 `json:"pumpkin_dif,omitempty"`
+	// Сводная оценка KPI по формуле региона, не хранится и заполняется при выдаче
+	Score *float64 `json:"score,omitempty" gorm:"-"`
+}
+
+// Продукт каталога (Семечка, Тыква, Арахис, ...)
+type Product struct {
+	ID       uint   `json:"id"       gorm:"primarykey"`
+	Code     string `json:"code"     gorm:"not null;unique"`
+	Name     string `json:"name"     gorm:"not null"`
+	Position int    `json:"position" gorm:"not null;default:0"`
+	Active   bool   `json:"active"   gorm:"not null"`
+}
 
-	PeanutPlan float64 `json:"peanut_plan"`
-	PeanutFact float64 `json:"peanut_fact"`
-	PeanutDif  float64 `json:"peanut_dif,omitempty"`
+// План и факт одного продукта в отчете
+type StatProduct struct {
+	ID          uint    `json:"-"    gorm:"primarykey"`
+	StatDailyID uint    `json:"-"    gorm:"not null;uniqueIndex:idx_stat_product"`
+	ProductID   uint    `json:"-"    gorm:"not null;uniqueIndex:idx_stat_product"`
+	Code        string  `json:"code" gorm:"not null"`
+	Plan        float64 `json:"plan"`
+	Fact        float64 `json:"fact"`
+	Dif         float64 `json:"dif"`
+
+	// Откуда взят план: введен представителем или рассчитан из месячной цели
+	PlanSource string `json:"plan_source,omitempty" gorm:"not null;default:'client'"`
+	// План, введенный представителем и замененный расчетным, если они различались
+	ClaimedPlan *float64 `json:"claimed_plan,omitempty"`
+}
+
+// Источники плана по продукту
+const (
+	PlanSourceClient = "client"
+	PlanSourceTarget = "target"
+)
+
+// Типы KPI-счетчиков
+const (
+	MetricTypeCounter = "counter" // неотрицательное целое
+	MetricTypeFlag    = "flag"    // 0 или 1
+)
+
+// Определение KPI-счетчика (АКБ, Новые ТТ, ДМП, ...). RegionID = 0 - счетчик для всех регионов,
+// счетчик региона с тем же кодом переопределяет общий
+type MetricDefinition struct {
+	ID       uint   `json:"id"        gorm:"primarykey"`
+	RegionID uint   `json:"region_id" gorm:"not null;default:0;uniqueIndex:idx_metric_region_code"`
+	Code     string `json:"code"      gorm:"not null;uniqueIndex:idx_metric_region_code"`
+	Name     string `json:"name"      gorm:"not null"`
+	Type     string `json:"type"      gorm:"not null"`
+	Required bool   `json:"required"  gorm:"not null"`
+	Position int    `json:"position"  gorm:"not null;default:0"`
+	Active   bool   `json:"active"    gorm:"not null"`
+}
 
-	AKB1    int `json:"akb1,omitempty"`
-	AKB2    int `json:"akb2,omitempty"`
-	NewTT   int `json:"newtt,omitempty"`
-	Mix     int `json:"mix,omitempty"`
-	NpOne   int `json:"npone,omitempty"`
-	SetShel int `json:"set_shelving,omitempty"`
-	DMP     int `json:"dmp,omitempty"`
-	TopFive int `json:"top_five,omitempty"`
-	News    int `json:"news,omitempty"`
+// Значение KPI-счетчика в отчете
+type StatMetric struct {
+	ID          uint   `json:"-"     gorm:"primarykey"`
+	StatDailyID uint   `json:"-"     gorm:"not null;uniqueIndex:idx_stat_metric"`
+	Code        string `json:"code"  gorm:"not null;uniqueIndex:idx_stat_metric"`
+	Value       int    `json:"value"`
+}
+
+// Получить значения продукта по коду, nil если продукта нет в отчете
+func (s *StatDaily) Product(code string) *StatProduct {
+	for i := range s.Products {
+		if s.Products[i].Code == code {
+			return &s.Products[i]
+		}
+	}
+	return nil
+}
+
+// Получить значение счетчика по коду, nil если счетчика нет в отчете
+func (s *StatDaily) Metric(code string) *StatMetric {
+	for i := range s.Metrics {
+		if s.Metrics[i].Code == code {
+			return &s.Metrics[i]
+		}
+	}
+	return nil
+}
+
+// Пересчитать разницу факт-план по всем продуктам
+func (s *StatDaily) ComputeDifs() {
+	for i := range s.Products {
+		s.Products[i].Dif = s.Products[i].Fact - s.Products[i].Plan
+	}
 }
 
 type ErrorResponse struct {
 	Error string `json:"error" example:"error description"`
 }
+
+// ETag текущей версии отчета: "<id>.<version>"
+func (s *StatDaily) ETag() string {
+	return fmt.Sprintf(`"%d.%d"`, s.ID, s.Version)
+}
+
+// Совпадает ли значение заголовка If-Match с текущей версией отчета.
+// Поддерживаются списки тегов, слабые теги W/"..." и "*"
+func (s *StatDaily) MatchesETag(ifMatch string) bool {
+	etag := s.ETag()
+
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Виды уведомлений
+const (
+	NotificationDigest = "digest"
+	// Напоминание представителю о несданном отчете до срока
+	NotificationReminder = "reminder"
+	// Сообщение администраторам региона о несданных к сроку отчетах
+	NotificationEscalation = "escalation"
+)
+
+// Состояния доставки уведомления
+const (
+	NotificationPending = "pending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+)
+
+// Журнал уведомлений: одна запись на вид, регион, дату, получателя и канал.
+// Запись создается до отправки и не дает отправить то же уведомление повторно
+// после перезапуска или с другой реплики
+type NotificationLog struct {
+	ID       uint   `json:"id"        gorm:"primarykey"`
+	Kind     string `json:"kind"      gorm:"not null;uniqueIndex:idx_notification"`
+	RegionID uint   `json:"region_id" gorm:"not null;uniqueIndex:idx_notification"`
+	Date     string `json:"date"      gorm:"type:date;not null;uniqueIndex:idx_notification"`
+	// Пользователь-получатель
+	Recipient string `json:"recipient" gorm:"not null;uniqueIndex:idx_notification"`
+	Channel   string `json:"channel"   gorm:"not null;uniqueIndex:idx_notification"`
+	Address   string `json:"address"`
+	Status    string `json:"status"    gorm:"not null"`
+	Error     string `json:"error,omitempty"`
+	Attempts  int    `json:"attempts"  gorm:"not null;default:0"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Виды событий изменения отчетов для агрегатов summa
+const (
+	StatEventAdd    = "add"
+	StatEventUpdate = "update"
+	StatEventRemove = "remove"
+	// Удаление отчетов региона раньше даты Date по сроку хранения
+	StatEventPurge = "purge"
+)
+
+// Событие outbox: пишется в одной транзакции с изменением отчета
+// и применяется к агрегатам только после коммита
+type StatEvent struct {
+	ID       uint   `gorm:"primarykey"`
+	Kind     string `gorm:"not null"`
+	RegionID uint   `gorm:"not null"`
+	Date     string `gorm:"type:date;not null"`
+
+	OldData json.RawMessage `gorm:"type:jsonb"`
+	NewData json.RawMessage `gorm:"type:jsonb"`
+
+	CreatedAt   time.Time
+	ProcessedAt *time.Time `gorm:"index"`
+}
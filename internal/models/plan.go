@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Месячная цель по продукту для пользователя или, при пустом Name, для региона целиком
+type PlanTarget struct {
+	ID        uint    `json:"id"        gorm:"primarykey"`
+	Month     string  `json:"month"     gorm:"type:date;not null;uniqueIndex:idx_plan_target"`
+	RegionID  uint    `json:"region_id" gorm:"not null;uniqueIndex:idx_plan_target"`
+	Name      string  `json:"name"      gorm:"not null;default:'';uniqueIndex:idx_plan_target"`
+	ProductID uint    `json:"-"         gorm:"not null;uniqueIndex:idx_plan_target"`
+	Code      string  `json:"code"      gorm:"not null"`
+	Amount    float64 `json:"amount"   gorm:"not null"`
+
+	// Веса дней месяца (YYYY-MM-DD -> вес). Дни без веса считаются с весом 1,
+	// если они рабочие, и 0, если выходные
+	Weights map[string]float64 `json:"weights,omitempty" gorm:"serializer:json;type:jsonb"`
+
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MarshalJSON отдает месяц цели в виде YYYY-MM
+func (t PlanTarget) MarshalJSON() ([]byte, error) {
+	type planTarget PlanTarget
+
+	target := planTarget(t)
+	if len(target.Month) >= len("2006-01") {
+		target.Month = target.Month[:len("2006-01")]
+	}
+
+	return json.Marshal(target)
+}
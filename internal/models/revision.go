@@ -0,0 +1,59 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Действия над отчетом, фиксируемые в истории
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionRestore = "restore"
+)
+
+// Кто выполняет изменение отчета
+type Actor struct {
+	Username string
+	Role     string
+	IP       string
+}
+
+// Неизменяемая запись истории отчета: состояние до и после изменения в формате API.
+// Ссылка на отчет не внешний ключ, чтобы история переживала удаление отчета
+type StatRevision struct {
+	ID          uint   `json:"id"        gorm:"primarykey"`
+	StatDailyID uint   `json:"report_id" gorm:"not null;index"`
+	RegionID    uint   `json:"region_id" gorm:"not null;index:idx_stat_revision_report"`
+	Name        string `json:"name"      gorm:"not null;index:idx_stat_revision_report"`
+	Date        string `json:"date"      gorm:"type:date;not null;index:idx_stat_revision_report"`
+
+	Action string `json:"action" gorm:"not null"`
+	Actor  string `json:"actor"  gorm:"not null"`
+	IP     string `json:"ip"`
+
+	// Пустое OldData у создания, пустое NewData у удаления
+	OldData json.RawMessage `json:"old,omitempty" gorm:"type:jsonb"`
+	NewData json.RawMessage `json:"new,omitempty" gorm:"type:jsonb"`
+
+	// Ревизия, из которой восстановлен отчет
+	RestoredFrom *uint `json:"restored_from,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Состояние отчета, сохраненное в ревизии: после изменения, а для удаления - до него
+func (r *StatRevision) Snapshot() (*StatDaily, error) {
+	data := r.NewData
+	if len(data) == 0 {
+		data = r.OldData
+	}
+
+	var stat StatDaily
+	if err := json.Unmarshal(data, &stat); err != nil {
+		return nil, err
+	}
+
+	return &stat, nil
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Значение отчета, из которого считается слагаемое оценки
+const (
+	// Процент выполнения плана продукта (факт / план * 100)
+	ScoreSourcePercent = "percent"
+	ScoreSourcePlan    = "plan"
+	ScoreSourceFact    = "fact"
+	ScoreSourceDif     = "dif"
+	// Значение KPI-счетчика
+	ScoreSourceMetric = "metric"
+)
+
+// Слагаемое формулы оценки: Weight баллов за единицу значения (не больше Cap)
+// плюс Bonus, если значение достигло Threshold. Ниже порога слагаемое равно нулю
+type ScoreTerm struct {
+	Source    string   `json:"source"`
+	Code      string   `json:"code"`
+	Weight    float64  `json:"weight"`
+	Cap       *float64 `json:"cap,omitempty"`
+	Threshold *float64 `json:"threshold,omitempty"`
+	Bonus     float64  `json:"bonus,omitempty"`
+}
+
+// Формула сводной оценки KPI региона. RegionID 0 - формула для регионов без своей
+type ScoringFormula struct {
+	ID        uint        `json:"id"         gorm:"primarykey"`
+	RegionID  uint        `json:"region_id"  gorm:"not null;uniqueIndex"`
+	Name      string      `json:"name"`
+	Terms     []ScoreTerm `json:"terms"      gorm:"serializer:json;type:jsonb;not null"`
+	UpdatedBy string      `json:"updated_by"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
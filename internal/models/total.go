@@ -0,0 +1,43 @@
+package models
+
+import "encoding/json"
+
+// Агрегат отчетов пользователя или региона за период
+type StatTotal struct {
+	Name     string `json:"name,omitempty"`
+	RegionID uint   `json:"region_id"`
+	Reports  int    `json:"reports"`
+	// Сводная оценка KPI суммы отчетов
+	Score *float64 `json:"score,omitempty"`
+
+	Products []StatProduct `json:"-"`
+	Metrics  []StatMetric  `json:"-"`
+}
+
+// Собрать агрегат из суммы отчетов
+func NewStatTotal(name string, regionID uint, reports int, stat StatDaily) StatTotal {
+	return StatTotal{
+		Name:     name,
+		RegionID: regionID,
+		Reports:  reports,
+		Products: stat.Products,
+		Metrics:  stat.Metrics,
+	}
+}
+
+// Представить агрегат как отчет, например для повторного суммирования
+func (t *StatTotal) Stat() StatDaily {
+	return StatDaily{Name: t.Name, RegionID: t.RegionID, Products: t.Products, Metrics: t.Metrics}
+}
+
+// MarshalJSON разворачивает суммы в те же плоские поля, что и у ежедневного отчета
+func (t StatTotal) MarshalJSON() ([]byte, error) {
+	type statTotal StatTotal
+
+	data, err := json.Marshal(statTotal(t))
+	if err != nil {
+		return nil, err
+	}
+
+	return flatten(data, t.Products, t.Metrics)
+}